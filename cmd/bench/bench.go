@@ -0,0 +1,203 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/urfave/cli"
+	"jrubin.io/slog"
+	"jrubin.io/zb/cmd"
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbbench"
+	"jrubin.io/zb/lib/zbcontext"
+	"jrubin.io/zb/lib/zbtest"
+)
+
+// Cmd is the bench command
+var Cmd cmd.Constructor = &cc{}
+
+type cc struct {
+	zbbench.ZBBench
+	List bool
+}
+
+func (co *cc) New(*cli.App) cli.Command {
+	return cli.Command{
+		Name:      "bench",
+		Usage:     "benchmark all of the packages in each of the projects and cache the results",
+		ArgsUsage: "[build/test flags] [packages]",
+		Action: func(c *cli.Context) error {
+			ctx := cmd.Context(c)
+			return co.run(ctx, c.App.Writer, c.Args()...)
+		},
+		Flags: append(co.TestFlags(), []cli.Flag{
+			cli.BoolFlag{
+				Name:        "f",
+				Destination: &co.Force,
+				Usage:       "treat all benchmark results as uncached",
+			},
+			cli.BoolFlag{
+				Name:        "l",
+				Destination: &co.List,
+				Usage:       "list the uncached packages it would benchmark",
+			},
+			cli.StringFlag{
+				Name:        "save",
+				Destination: &co.Save,
+				Usage:       "save the results as a named baseline",
+			},
+			cli.StringFlag{
+				Name:        "compare",
+				Destination: &co.Compare,
+				Usage:       "compare the results against a named baseline",
+			},
+			cli.Float64Flag{
+				Name:        "threshold",
+				Destination: &co.Threshold,
+				Value:       10,
+				Usage: `
+
+				exit with a non-zero status when comparing against a baseline
+				and any benchmark is worse by more than this percent. When
+				-count is greater than 1 only statistically significant
+				differences are considered.`,
+			},
+		}...),
+	}
+}
+
+func (co *cc) run(ctx zbcontext.Context, w io.Writer, args ...string) error {
+	ctx = co.BenchSetup(ctx)
+
+	var baseline zbbench.Results
+	if co.Compare != "" {
+		var err error
+		if baseline, err = zbbench.LoadBaseline(ctx, co.Compare); err != nil {
+			return err
+		}
+	}
+
+	var pkgs, toRun project.Packages
+	var err error
+
+	if ctx.Package {
+		pkgs, toRun, err = co.runPackages(ctx, args...)
+	} else {
+		pkgs, toRun, err = co.runProjects(ctx, args...)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if co.List {
+		for _, pkg := range toRun {
+			fmt.Fprintf(w, "%s\n", pkg.ImportPath)
+		}
+		return nil
+	}
+
+	results, code, err := co.runBench(ctx, w, pkgs, toRun)
+	if err != nil {
+		return err
+	}
+
+	if co.Save != "" {
+		if err = zbbench.SaveBaseline(ctx, co.Save, results); err != nil {
+			return err
+		}
+		ctx.Logger.WithField("name", co.Save).Info("saved baseline")
+	}
+
+	if baseline != nil {
+		comparisons := zbbench.Compare(baseline, results)
+
+		fmt.Fprintln(w)
+		if err = zbbench.WriteComparisons(w, co.Compare, comparisons, co.Threshold); err != nil {
+			return err
+		}
+
+		var regressions int
+		for _, c := range comparisons {
+			if c.Regression(co.Threshold) {
+				regressions++
+			}
+		}
+
+		if regressions > 0 {
+			ctx.Logger.WithFields(slog.Fields{
+				"regressions": regressions,
+				"threshold":   fmt.Sprintf("%g%%", co.Threshold),
+			}).Error("benchmark regressions exceed threshold")
+			if code == zbcontext.ExitOK {
+				code = zbcontext.ExitFailed
+			}
+		}
+	}
+
+	if code != zbcontext.ExitOK {
+		return cli.NewExitError("", code)
+	}
+
+	return nil
+}
+
+func (co *cc) runPackages(ctx zbcontext.Context, args ...string) (pkgs, toRun project.Packages, err error) {
+	pkgs, err = project.ListPackages(ctx, args...)
+	if err != nil {
+		return
+	}
+
+	return co.buildPackagesLists(ctx, pkgs)
+}
+
+func (co *cc) runProjects(ctx zbcontext.Context, args ...string) (pkgs, toRun project.Packages, err error) {
+	var projects project.List
+	projects, err = project.Projects(ctx, args...)
+	if err != nil {
+		return
+	}
+
+	return zbtest.ProjectsLists(ctx, co, projects, hasTests)
+}
+
+func (co *cc) buildPackagesLists(ctx zbcontext.Context, in project.Packages) (pkgs, toRun project.Packages, err error) {
+	return zbtest.PackagesLists(ctx, co, in, hasTests)
+}
+
+// hasTests reports whether the package has test files, benchmarks can only
+// exist in them
+func hasTests(pkg *project.Package) bool {
+	return len(pkg.TestGoFiles)+len(pkg.XTestGoFiles) > 0
+}
+
+func (co *cc) runBench(ctx zbcontext.Context, w io.Writer, pkgs, toRun project.Packages) (zbbench.Results, int, error) {
+	results := zbbench.Results{}
+
+	code, err := zbtest.Exec(ctx, w, co.BenchArgs(), nil, pkgs, toRun, func(pkg *project.Package, r zbtest.StringReader) error {
+		var data []byte
+		var err error
+
+		if r != nil {
+			data, err = co.ReadResult(ctx, r, pkg)
+		} else {
+			data, err = co.ShowResult(ctx, w, pkg)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if res := zbbench.ParseResults(data); len(res) > 0 {
+			results[pkg.ImportPath] = res
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, code, err
+	}
+
+	return results, code, nil
+}
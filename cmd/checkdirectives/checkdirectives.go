@@ -0,0 +1,110 @@
+package checkdirectives
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+	"jrubin.io/zb/cmd"
+	"jrubin.io/zb/lib/dependency"
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// Cmd is the check-directives command
+var Cmd cmd.Constructor = &cc{}
+
+type cc struct{}
+
+func (co *cc) New(_ *cli.App) cli.Command {
+	return cli.Command{
+		Name:      "check-directives",
+		Usage:     "report problems with the zb:generate directives in each of the projects",
+		ArgsUsage: "[packages]",
+		Action: func(c *cli.Context) error {
+			ctx := cmd.Context(c)
+			ctx.ExcludeVendor = true
+			return co.run(ctx, c.App.Writer, c.Args()...)
+		},
+	}
+}
+
+func join(dir string, names ...[]string) []string {
+	var ret []string
+	for _, n := range names {
+		for _, name := range n {
+			ret = append(ret, filepath.Join(dir, name))
+		}
+	}
+	return ret
+}
+
+func pkgFiles(pkg *project.Package) []string {
+	return join(pkg.Dir, pkg.GoFiles, pkg.CgoFiles, pkg.TestGoFiles, pkg.XTestGoFiles)
+}
+
+// files returns the go files, including test files, of the packages and,
+// unless --package was given, those of the directories in the projects
+// without buildable go files
+func (co *cc) files(ctx zbcontext.Context, args ...string) ([]string, error) {
+	var files []string
+
+	if ctx.Package {
+		pkgs, err := project.ListPackages(ctx, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pkg := range pkgs {
+			files = append(files, pkgFiles(pkg)...)
+		}
+
+		return files, nil
+	}
+
+	projects, err := project.Projects(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range projects {
+		for _, pkg := range p.Packages {
+			files = append(files, pkgFiles(pkg)...)
+		}
+
+		dirs, err := p.GenerateDirs(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dir := range dirs {
+			files = append(files, join(dir.Dir, dir.IgnoredGoFiles)...)
+		}
+	}
+
+	return files, nil
+}
+
+func (co *cc) run(ctx zbcontext.Context, w io.Writer, args ...string) error {
+	files, err := co.files(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	problems, err := dependency.CheckDirectives(files)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		ctx.Logger.Info("no problems found")
+		return nil
+	}
+
+	for _, p := range problems {
+		fmt.Fprintln(w, p)
+	}
+
+	return cli.NewExitError("", zbcontext.ExitFailed)
+}
@@ -0,0 +1,224 @@
+package generate
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"jrubin.io/zb/lib/dependency"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// snapshot is the state of a generated file before go generate was run
+type snapshot struct {
+	path   string
+	data   []byte
+	mode   os.FileMode
+	exists bool
+}
+
+func takeSnapshot(path string) (*snapshot, error) {
+	s := snapshot{path: path}
+
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return &s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.data, err = ioutil.ReadFile(path); err != nil {
+		return nil, err
+	}
+
+	s.mode = fi.Mode()
+	s.exists = true
+
+	return &s, nil
+}
+
+// changed returns how the file differs from the snapshot, if at all
+func (s *snapshot) changed() (string, error) {
+	data, err := ioutil.ReadFile(s.path)
+	switch {
+	case os.IsNotExist(err):
+		if s.exists {
+			return "deleted", nil
+		}
+		return "", nil
+	case err != nil:
+		return "", err
+	case !s.exists:
+		return "created", nil
+	case string(data) != string(s.data):
+		return "modified", nil
+	}
+
+	return "", nil
+}
+
+func (s *snapshot) restore() error {
+	if !s.exists {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	return ioutil.WriteFile(s.path, s.data, s.mode)
+}
+
+// untracked returns the files, of those given, that are not tracked in their
+// git repositories and aren't ignored by them. git is used rather than go-git
+// since go-git doesn't apply .gitignore files.
+func untracked(files []string) (map[string]bool, error) {
+	byRepo := map[string][]string{}
+	for _, file := range files {
+		if dir := zbcontext.GitDir(filepath.Dir(file)); dir != "" {
+			byRepo[dir] = append(byRepo[dir], file)
+		}
+	}
+
+	ret := map[string]bool{}
+
+	for dir, files := range byRepo {
+		args := []string{"ls-files", "--others", "--exclude-standard", "-z", "--"}
+
+		for _, file := range files {
+			rel, err := filepath.Rel(dir, file)
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, rel)
+		}
+
+		ecmd := exec.Command("git", args...) // nosec
+		ecmd.Dir = dir
+
+		out, err := ecmd.Output()
+		if err != nil {
+			return nil, errors.Wrapf(err, "error getting untracked files of %s", dir)
+		}
+
+		for _, rel := range strings.Split(string(out), "\x00") {
+			if rel != "" {
+				ret[filepath.Join(dir, filepath.FromSlash(rel))] = true
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// rebuildAll is the BuildArger of the command with every target considered
+// stale, as with -a, but without passing -a to the go commands
+type rebuildAll struct {
+	*cc
+}
+
+func (rebuildAll) RebuildAll() bool {
+	return true
+}
+
+// check runs go generate for every file with zb:generate annotations and
+// reports the generated files that are not up to date in git. The targets are
+// built like they are with -a so that the tools they use are installed first
+// and -after directives are respected.
+func (co *cc) check(ctx zbcontext.Context, w io.Writer, targets []*dependency.Target) error {
+	var err error
+	snapshots := map[string]*snapshot{}
+	var outputs []string
+
+	for _, target := range targets {
+		gen, ok := target.Dependency.(*dependency.GoGenerateFile)
+		if !ok {
+			continue
+		}
+
+		if _, ok = snapshots[gen.Path]; ok {
+			continue
+		}
+
+		if snapshots[gen.Path], err = takeSnapshot(gen.Path); err != nil {
+			return err
+		}
+
+		outputs = append(outputs, gen.Path)
+	}
+
+	sort.Strings(outputs)
+
+	restore := func() error {
+		if !co.Restore {
+			return nil
+		}
+
+		for _, path := range outputs {
+			if err := snapshots[path].restore(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	ctx.BuildArger = rebuildAll{co}
+
+	if _, err = dependency.Build(ctx, dependency.TargetGenerate, targets); err != nil {
+		if rerr := restore(); rerr != nil {
+			ctx.Logger.WithError(rerr).Error("error restoring generated files")
+		}
+		return err
+	}
+
+	problems := map[string]string{}
+
+	for _, path := range outputs {
+		var change string
+		if change, err = snapshots[path].changed(); err != nil {
+			return err
+		}
+
+		if change != "" {
+			problems[path] = change
+		}
+	}
+
+	notTracked, err := untracked(outputs)
+	if err != nil {
+		return err
+	}
+
+	for path := range notTracked {
+		if _, ok := problems[path]; !ok {
+			problems[path] = "untracked"
+		}
+	}
+
+	if err = restore(); err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		ctx.Logger.Info("generated files are up to date")
+		return nil
+	}
+
+	for _, path := range outputs {
+		if problem, ok := problems[path]; ok {
+			fmt.Fprintf(w, "%s: %s\n", rel(path), problem)
+		}
+	}
+
+	return cli.NewExitError("", zbcontext.ExitFailed)
+}
@@ -0,0 +1,80 @@
+package generate
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUntracked(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "zbgenerate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	git := func(args ...string) {
+		args = append([]string{"-c", "user.name=zb", "-c", "user.email=zb@example.com", "-c", "commit.gpgsign=false"}, args...)
+
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	files := map[string]string{
+		".gitignore":       "*_mock.go\n",
+		"tracked.go":       "package a\n",
+		"a_mock.go":        "package a\n",
+		"new.go":           "package a\n",
+		"sub/.gitignore":   "gen/\n",
+		"sub/gen/gen.go":   "package gen\n",
+		"sub/untracked.go": "package sub\n",
+	}
+
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	git("add", ".gitignore", "tracked.go")
+	git("commit", "-q", "-m", "initial")
+
+	var paths []string
+	for _, name := range []string{"tracked.go", "a_mock.go", "new.go", "sub/gen/gen.go", "sub/untracked.go"} {
+		paths = append(paths, filepath.Join(dir, filepath.FromSlash(name)))
+	}
+
+	got, err := untracked(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ignored outputs are not untracked
+	want := map[string]bool{
+		filepath.Join(dir, "new.go"):              true,
+		filepath.Join(dir, "sub", "untracked.go"): true,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("untracked = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package generate
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+	"jrubin.io/zb/cmd"
+	"jrubin.io/zb/cmd/install"
+	"jrubin.io/zb/lib/buildflags"
+	"jrubin.io/zb/lib/dependency"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// Cmd is the generate command
+var Cmd cmd.Constructor = &cc{}
+
+type cc struct {
+	buildflags.Data
+	Check   bool
+	Restore bool
+}
+
+func (co *cc) New(*cli.App) cli.Command {
+	return cli.Command{
+		Name:      "generate",
+		Usage:     "run go generate for the stale zb:generate targets in each of the projects",
+		ArgsUsage: "[build flags] [packages]",
+		Action: func(c *cli.Context) error {
+			ctx := cmd.Context(c)
+			ctx.BuildContext = co.Data.BuildContext()
+			ctx.BuildArger = co
+			ctx.GenerateTests = true
+
+			targets, err := install.Targets(ctx, dependency.TargetGenerate, c.Args()...)
+			if err != nil {
+				return err
+			}
+
+			for _, path := range dependency.Unmanaged(ctx, targets) {
+				ctx.Logger.WithField("file", rel(path)).Warn("generated file has no zb:generate directive")
+			}
+
+			if co.Check {
+				return co.check(ctx, c.App.Writer, targets)
+			}
+
+			if co.N {
+				return dryRun(ctx, c.App.Writer, targets)
+			}
+
+			return install.Build(ctx, dependency.TargetGenerate, targets)
+		},
+		Flags: append(co.BuildFlags(true),
+			cli.BoolFlag{
+				Name:        "check",
+				Usage:       "run go generate for every file with zb:generate annotations and fail if any generated file was created, modified or is untracked in git",
+				Destination: &co.Check,
+			},
+			cli.BoolFlag{
+				Name:        "restore",
+				Usage:       "with --check, restore the generated files to their original state instead of leaving the changes",
+				Destination: &co.Restore,
+			},
+		),
+	}
+}
+
+func rel(path string) string {
+	if r, err := filepath.Rel(zbcontext.CWD, path); err == nil && len(r) < len(path) {
+		return r
+	}
+	return path
+}
+
+func dryRun(ctx zbcontext.Context, w io.Writer, targets []*dependency.Target) error {
+	stale, err := dependency.StaleTargets(ctx, dependency.TargetGenerate, targets)
+	if err != nil {
+		return err
+	}
+
+	if len(stale) == 0 {
+		ctx.Logger.Info("nothing to generate")
+		return nil
+	}
+
+	for _, s := range stale {
+		switch dep := s.Dependency.(type) {
+		case *dependency.GoGenerateFile:
+			fmt.Fprintf(w, "go generate %s # %s\n", rel(dep.GoFile.Path), s.Reason())
+		case *dependency.GoPackage:
+			fmt.Fprintf(w, "go install %s # %s\n", dep.ImportPath, s.Reason())
+		}
+	}
+
+	return nil
+}
@@ -32,15 +32,17 @@ func (co *cc) New(_ *cli.App) cli.Command {
 }
 
 func Run(ctx zbcontext.Context, tt dependency.TargetType, args ...string) error {
-	var err error
-	var built int
-
-	if ctx.Package {
-		built, err = installPackage(ctx, tt, args...)
-	} else {
-		built, err = installProject(ctx, tt, args...)
+	targets, err := Targets(ctx, tt, args...)
+	if err != nil {
+		return err
 	}
 
+	return Build(ctx, tt, targets)
+}
+
+// Build builds the stale targets and logs if there were none
+func Build(ctx zbcontext.Context, tt dependency.TargetType, targets []*dependency.Target) error {
+	built, err := dependency.Build(ctx, tt, targets)
 	if err != nil {
 		return err
 	}
@@ -52,20 +54,22 @@ func Run(ctx zbcontext.Context, tt dependency.TargetType, args ...string) error
 	return nil
 }
 
-func installPackage(ctx zbcontext.Context, tt dependency.TargetType, args ...string) (int, error) {
-	pkgs, err := project.ListPackages(ctx, args...)
-	if err != nil {
-		return 0, err
-	}
+// Targets returns the targets, in dependency order, of the packages or, unless
+// --package was given, of all of the packages in their projects
+func Targets(ctx zbcontext.Context, tt dependency.TargetType, args ...string) ([]*dependency.Target, error) {
+	if ctx.Package {
+		pkgs, err := project.ListPackages(ctx, args...)
+		if err != nil {
+			return nil, err
+		}
 
-	return pkgs.Build(ctx, tt)
-}
+		return pkgs.Targets(ctx, tt)
+	}
 
-func installProject(ctx zbcontext.Context, tt dependency.TargetType, args ...string) (int, error) {
 	projects, err := project.Projects(ctx, args...)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return projects.Build(ctx, tt)
+	return projects.Targets(ctx, tt)
 }
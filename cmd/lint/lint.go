@@ -3,9 +3,6 @@ package lint
 import (
 	"fmt"
 	"io"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"sort"
 	"strings"
 
@@ -28,7 +25,7 @@ type cc struct {
 func (co *cc) New(*cli.App) cli.Command {
 	return cli.Command{
 		Name:      "lint",
-		Usage:     "gometalinter with cache and better defaults",
+		Usage:     "lint all of the packages in each of the projects and cache the results",
 		ArgsUsage: "[arguments] [packages]",
 		Action: func(c *cli.Context) error {
 			ctx := cmd.Context(c)
@@ -46,15 +43,56 @@ func (co *cc) New(*cli.App) cli.Command {
 				Usage: fmt.Sprintf("Filter out lint lines from files that have these suffixes (default: %s)", strings.Join(zblint.DefaultIgnoreSuffixes, ",")),
 				Value: &co.IgnoreSuffixes,
 			},
+			cli.BoolFlag{
+				Name:        "lint-generated",
+				Usage:       "Do not filter out lint lines from files with a \"Code generated ... DO NOT EDIT.\" header",
+				Destination: &co.LintGenerated,
+			},
+			cli.BoolFlag{
+				Name:        "warn-unused-nolint",
+				Usage:       "Warn about zb:nolint directives that don't suppress any issue or don't give a reason",
+				Destination: &co.WarnNolint,
+			},
+			cli.StringFlag{
+				Name:        "sarif",
+				Usage:       "Also write the results, as a SARIF 2.1.0 log, to this file",
+				Destination: &co.SARIF,
+			},
+			cli.BoolFlag{
+				Name:        "baseline",
+				Usage:       fmt.Sprintf("Only report issues that are not recorded in the %s file of the project", zblint.BaselineFile),
+				Destination: &co.Baseline,
+			},
+			cli.BoolFlag{
+				Name:        "write-baseline",
+				Usage:       fmt.Sprintf("Record all current issues in the %s file of the project", zblint.BaselineFile),
+				Destination: &co.WriteBaseline,
+			},
+			cli.StringFlag{
+				Name:        "new-from-rev",
+				Usage:       "Only report issues on lines that were added or modified since this git revision",
+				Destination: &co.NewFromRev,
+			},
+			cli.BoolFlag{
+				Name:        "fix",
+				Usage:       "Apply gofmt, goimports and misspell fixes to the source before linting",
+				Destination: &co.Fix,
+			},
+			cli.BoolFlag{
+				Name:        "summary",
+				Usage:       "Print the number of issues by linter, severity, package and file",
+				Destination: &co.Summary,
+			},
+			cli.BoolFlag{
+				Name:        "summary-json",
+				Usage:       "Like --summary but output JSON instead of the issues, so that the output can be parsed",
+				Destination: &co.SummaryJSON,
+			},
 		),
 	}
 }
 
 func (co *cc) run(ctx zbcontext.Context, w io.Writer, args ...string) error {
-	if _, err := exec.LookPath("gometalinter"); err != nil {
-		return err
-	}
-
 	if ctx.Package {
 		return co.runPackage(ctx, w, args...)
 	}
@@ -68,6 +106,10 @@ func (co *cc) runPackage(ctx zbcontext.Context, w io.Writer, args ...string) err
 		return err
 	}
 
+	if err = co.ResolveBackend(pkgs); err != nil {
+		return err
+	}
+
 	pkgs, toRun, err := co.buildListsPackages(ctx, pkgs)
 	if err != nil {
 		return err
@@ -82,6 +124,15 @@ func (co *cc) runProject(ctx zbcontext.Context, w io.Writer, args ...string) err
 		return err
 	}
 
+	var all project.Packages
+	for _, proj := range projects {
+		all = all.Append(proj.Packages)
+	}
+
+	if err = co.ResolveBackend(all); err != nil {
+		return err
+	}
+
 	pkgs, toRun, err := co.buildListsProjects(ctx, projects)
 	if err != nil {
 		return err
@@ -93,36 +144,107 @@ func (co *cc) runProject(ctx zbcontext.Context, w io.Writer, args ...string) err
 func (co *cc) exec(ctx zbcontext.Context, w io.Writer, pkgs, toRun project.Packages) error {
 	code := zbcontext.ExitOK
 
-	for _, pkg := range pkgs {
-		file, err := co.CacheFile(ctx, pkg)
+	if co.Fix {
+		touched, err := co.ApplyFixes(ctx, pkgs)
 		if err != nil {
 			return err
 		}
 
-		if len(toRun) > 0 && toRun[0] == pkg {
-			path := pkg.Package.Dir
-			if rel, err := filepath.Rel(zbcontext.CWD, path); err == nil {
-				path = rel
-			}
+		// packages that were modified need to be linted again
+		for _, pkg := range touched {
+			toRun.Insert(pkg)
+		}
+	}
+
+	fresh := map[*project.Package]*zblint.Result{}
+
+	if len(toRun) > 0 {
+		backend, err := co.Backend()
+		if err != nil {
+			return err
+		}
 
-			ecode, err := co.runLinter(ctx, w, path, file)
+		for _, batch := range zblint.Batches(toRun) {
+			results, ecode, err := co.runLinter(ctx, backend, batch)
 			if err != nil {
 				return err
 			}
+
 			if code == zbcontext.ExitOK {
 				code = ecode
 			}
 
-			toRun = toRun[1:]
-		} else {
-			failed, err := co.ShowResult(w, file)
+			for pkg, res := range results {
+				fresh[pkg] = res
+			}
+		}
+	}
+
+	var issues []zblint.Issue
+
+	for _, pkg := range pkgs {
+		res, ok := fresh[pkg]
+		if !ok {
+			file, err := co.CacheFile(ctx, pkg)
 			if err != nil {
 				return err
 			}
-			if code == zbcontext.ExitOK && failed {
-				code = zbcontext.ExitFailed
+
+			if res, err = co.LoadResult(file); err != nil {
+				return err
 			}
 		}
+
+		if err := co.writeOutput(ctx, w, res.Output); err != nil {
+			return err
+		}
+
+		issues = append(issues, co.Filter(res.Issues)...)
+	}
+
+	issues = co.ApplyNolint(ctx, issues, pkgs)
+
+	if co.WriteBaseline {
+		if err := co.WriteBaselines(ctx, issues, pkgs); err != nil {
+			return err
+		}
+	}
+
+	var err error
+
+	if co.Baseline || co.WriteBaseline {
+		if issues, err = co.ApplyBaselines(ctx, issues, pkgs); err != nil {
+			return err
+		}
+	}
+
+	if issues, err = co.FilterNewFromRev(ctx, issues); err != nil {
+		return err
+	}
+
+	// the JSON summary is the only output so that it can be parsed
+	if !co.SummaryJSON {
+		if err = co.WriteIssues(w, issues); err != nil {
+			return err
+		}
+	}
+
+	if err = co.SaveSARIF(issues); err != nil {
+		return err
+	}
+
+	if co.SummaryJSON {
+		if err = zblint.Summarize(issues, pkgs).WriteJSON(w); err != nil {
+			return err
+		}
+	} else if co.Summary {
+		if err = zblint.Summarize(issues, pkgs).Write(w); err != nil {
+			return err
+		}
+	}
+
+	if code == zbcontext.ExitOK && len(issues) > 0 {
+		code = zbcontext.ExitFailed
 	}
 
 	if code != zbcontext.ExitOK {
@@ -132,6 +254,26 @@ func (co *cc) exec(ctx zbcontext.Context, w io.Writer, pkgs, toRun project.Packa
 	return nil
 }
 
+// writeOutput writes any output from the linters that was not an issue. When
+// issues, or the summary, are being output in a machine readable format, the
+// output is logged instead so that it doesn't corrupt the results.
+func (co *cc) writeOutput(ctx zbcontext.Context, w io.Writer, output string) error {
+	if output == "" {
+		return nil
+	}
+
+	if co.OutputFormat() == zblint.FormatText && !co.SummaryJSON {
+		_, err := io.WriteString(w, output)
+		return err
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		ctx.Logger.Warn(line)
+	}
+
+	return nil
+}
+
 func (co *cc) buildListsPackages(ctx zbcontext.Context, in project.Packages) (pkgs, toRun project.Packages, err error) {
 	for _, pkg := range in {
 		if pkg.IsVendored {
@@ -173,37 +315,20 @@ func (co *cc) buildListsProjects(ctx zbcontext.Context, projects project.List) (
 	return
 }
 
-func (co *cc) runLinter(ctx zbcontext.Context, w io.Writer, path, cacheFile string) (int, error) {
+// runLinter lints a batch of packages with a single run of the backend and
+// caches the result of each package. The returned exit code is only non-zero
+// if the backend failed without reporting any issues, since issues may yet be
+// filtered out.
+func (co *cc) runLinter(ctx zbcontext.Context, backend zblint.Backend, pkgs project.Packages) (map[*project.Package]*zblint.Result, int, error) {
 	code := zbcontext.ExitOK
 
-	if err := os.MkdirAll(ctx.CacheDir, 0700); err != nil {
-		return code, err
-	}
-
-	args := co.LintArgs()
-	args = append(args, path)
-
-	ctx.Logger.Debug(zbcontext.QuoteCommand("→ gometalinter", args))
-
 	pr, pw := io.Pipe()
 
-	ecmd := exec.Command("gometalinter", args...) // nosec
-	ecmd.Stdout = pw
-	ecmd.Stderr = pw
-
-	if err := ecmd.Start(); err != nil {
-		return code, err
-	}
-
 	var group errgroup.Group
 	group.Go(func() error {
 		defer func() { _ = pw.Close() }() // nosec
 
-		if ecmd == nil {
-			return nil
-		}
-
-		ecode, err := zbcontext.ExitCode(ecmd.Wait())
+		ecode, err := backend.Run(ctx, pw, pkgs)
 		if err != nil {
 			return err
 		}
@@ -215,13 +340,34 @@ func (co *cc) runLinter(ctx zbcontext.Context, w io.Writer, path, cacheFile stri
 		return nil
 	})
 
-	if err := co.ReadResult(w, pr, cacheFile); err != nil {
-		return code, err
+	res, err := zblint.ReadResult(pr)
+	if err != nil {
+		return nil, code, err
+	}
+
+	if err = group.Wait(); err != nil {
+		return nil, code, err
+	}
+
+	results := co.SplitResult(ctx, pkgs, res)
+
+	// a backend that failed without reporting any issues can't be filtered
+	// into success. don't cache the results either, they are likely to be
+	// problems running the linter rather than with the packages.
+	if code != zbcontext.ExitOK && len(res.Issues) == 0 {
+		return results, code, nil
 	}
 
-	if err := group.Wait(); err != nil {
-		return code, err
+	for _, pkg := range pkgs {
+		file, err := co.CacheFile(ctx, pkg)
+		if err != nil {
+			return nil, code, err
+		}
+
+		if err = co.SaveResult(file, results[pkg]); err != nil {
+			return nil, code, err
+		}
 	}
 
-	return code, nil
+	return results, zbcontext.ExitOK, nil
 }
@@ -0,0 +1,181 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbconfig"
+	"jrubin.io/zb/lib/zbcontext"
+	"jrubin.io/zb/lib/zbtest"
+)
+
+// matrixProject is the packages of a project along with its matrix
+// configurations
+type matrixProject struct {
+	dir     string
+	configs []zbconfig.NamedMatrix
+	pkgs    project.Packages
+}
+
+// matrixProjects groups the packages by project and reads the matrix
+// configurations of each project, every one of which must have some
+func matrixProjects(pkgs project.Packages) ([]*matrixProject, error) {
+	var ret []*matrixProject
+	byDir := map[string]*matrixProject{}
+
+	for _, pkg := range pkgs {
+		dir := zbcontext.GitDir(pkg.Dir)
+
+		mp, ok := byDir[dir]
+		if !ok {
+			configs, err := zbconfig.GetMatrixConfigs(dir)
+			if err != nil {
+				return nil, err
+			}
+
+			mp = &matrixProject{dir: dir, configs: configs}
+			byDir[dir] = mp
+			ret = append(ret, mp)
+		}
+
+		mp.pkgs.Insert(pkg)
+	}
+
+	return ret, nil
+}
+
+// runMatrix tests the packages of each project with each of the matrix
+// variants configured by that project. The packages, and their hashes, are
+// shared by all of the variants.
+func (co *cc) runMatrix(ctx zbcontext.Context, w io.Writer, pkgs project.Packages) error {
+	projects, err := matrixProjects(pkgs)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	results := map[string]map[string]*zbtest.Status{}
+	code := zbcontext.ExitOK
+
+	for _, mp := range projects {
+		for _, config := range mp.configs {
+			if _, ok := results[config.Name]; !ok {
+				names = append(names, config.Name)
+				results[config.Name] = map[string]*zbtest.Status{}
+			}
+
+			co.Variant = &zbtest.Variant{
+				Name:  config.Name,
+				Flags: config.Flag,
+				Tags:  config.Tags,
+				Env:   config.Env,
+			}
+
+			var toRun project.Packages
+			for _, pkg := range mp.pkgs {
+				found, err := co.HaveResult(ctx, pkg)
+				if err != nil {
+					return err
+				}
+
+				if !found {
+					toRun.Insert(pkg)
+				}
+			}
+
+			if co.List {
+				for _, pkg := range toRun {
+					fmt.Fprintf(w, "%s\t%s\n", config.Name, pkg.ImportPath)
+				}
+				continue
+			}
+
+			fmt.Fprintf(w, "=== matrix: %s\n", config.Name)
+
+			statuses, ecode, err := co.execTest(ctx, w, mp.pkgs, toRun)
+			if err != nil {
+				return err
+			}
+
+			for importPath, status := range statuses {
+				results[config.Name][importPath] = status
+			}
+
+			if code == zbcontext.ExitOK {
+				code = ecode
+			}
+		}
+	}
+
+	co.Variant = nil
+
+	if len(names) == 0 {
+		return errors.Errorf("no matrix configurations found in %s", zbconfig.FileName)
+	}
+
+	if co.List {
+		return nil
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintln(w)
+	if err := writeMatrix(w, names, pkgs, results); err != nil {
+		return err
+	}
+
+	if code != zbcontext.ExitOK {
+		return cli.NewExitError("", code)
+	}
+
+	return nil
+}
+
+// writeMatrix writes a table of the result of each package with each of the
+// named configurations, "-" if its project doesn't have the configuration
+func writeMatrix(w io.Writer, names []string, pkgs project.Packages, results map[string]map[string]*zbtest.Status) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "PACKAGE")
+	for _, name := range names {
+		fmt.Fprintf(tw, "\t%s", name)
+	}
+	fmt.Fprintf(tw, "\t\n")
+
+	for _, pkg := range pkgs {
+		fmt.Fprintf(tw, "%s", pkg.ImportPath)
+		for _, name := range names {
+			fmt.Fprintf(tw, "\t%s", cell(results[name][pkg.ImportPath]))
+		}
+		fmt.Fprintf(tw, "\t\n")
+	}
+
+	return tw.Flush()
+}
+
+func cell(s *zbtest.Status) string {
+	if s == nil {
+		return "-"
+	}
+
+	result := s.Result
+	if result == "?" {
+		return "no tests"
+	}
+
+	if s.Elapsed != "" {
+		result += " " + s.Elapsed
+	}
+
+	if s.Cached {
+		result += " (cached)"
+	}
+
+	return result
+}
@@ -1,17 +1,14 @@
 package test
 
 import (
-	"bufio"
 	"fmt"
 	"io"
-	"os"
-	"os/exec"
-
-	"golang.org/x/sync/errgroup"
 
 	"github.com/urfave/cli"
 	"jrubin.io/zb/cmd"
+	"jrubin.io/zb/lib/dependency"
 	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbconfig"
 	"jrubin.io/zb/lib/zbcontext"
 	"jrubin.io/zb/lib/zbtest"
 )
@@ -23,7 +20,11 @@ var Cmd cmd.Constructor = &cc{}
 
 type cc struct {
 	zbtest.ZBTest
-	List bool
+	List     bool
+	Report   string
+	Top      int
+	Matrix   bool
+	Generate bool
 }
 
 func (co *cc) New(*cli.App) cli.Command {
@@ -49,6 +50,38 @@ func (co *cc) New(*cli.App) cli.Command {
 				Destination: &co.List,
 				Usage:       "list the uncached tests it would run",
 			},
+			cli.StringFlag{
+				Name:        "report",
+				Destination: &co.Report,
+				Usage: `
+
+				instead of running tests, show a report built from the
+				durations recorded by previous runs. Available reports: slow`,
+			},
+			cli.IntFlag{
+				Name:        "top",
+				Destination: &co.Top,
+				Value:       10,
+				Usage:       "limit reports to this many packages and tests (0 for all)",
+			},
+			cli.BoolFlag{
+				Name:        "matrix",
+				Destination: &co.Matrix,
+				Usage: `
+
+				run the tests once for each of the matrix configurations in the
+				project's ` + zbconfig.FileName + ` file and show a combined
+				table of the results`,
+			},
+			cli.BoolFlag{
+				Name:        "generate",
+				Destination: &co.Generate,
+				Usage: `
+
+				before testing, run go generate for the stale zb:generate
+				annotations of the packages, including those in test files.
+				generators are not run unless this is given`,
+			},
 		}...),
 	}
 }
@@ -56,6 +89,10 @@ func (co *cc) New(*cli.App) cli.Command {
 func (co *cc) run(ctx zbcontext.Context, w io.Writer, args ...string) error {
 	ctx = co.TestSetup(ctx)
 
+	if co.Report != "" {
+		return co.ShowReport(ctx, w, co.Report, co.Top)
+	}
+
 	var pkgs, toRun project.Packages
 	var err error
 
@@ -69,6 +106,10 @@ func (co *cc) run(ctx zbcontext.Context, w io.Writer, args ...string) error {
 		return err
 	}
 
+	if co.Matrix {
+		return co.runMatrix(ctx, w, pkgs)
+	}
+
 	if co.List {
 		for _, pkg := range toRun {
 			fmt.Fprintf(w, "%s\n", pkg.ImportPath)
@@ -85,6 +126,10 @@ func (co *cc) runPackages(ctx zbcontext.Context, w io.Writer, args ...string) (p
 		return
 	}
 
+	if err = co.generate(ctx, pkgs); err != nil {
+		return
+	}
+
 	return co.buildPackagesLists(ctx, pkgs)
 }
 
@@ -95,120 +140,98 @@ func (co *cc) runProjects(ctx zbcontext.Context, w io.Writer, args ...string) (p
 		return
 	}
 
+	if err = co.generate(ctx, projects); err != nil {
+		return
+	}
+
 	return co.buildProjectsLists(ctx, projects)
 }
 
-func (co *cc) buildPackagesLists(ctx zbcontext.Context, in project.Packages) (pkgs, toRun project.Packages, err error) {
-	for _, pkg := range in {
-		if pkg.IsVendored {
-			continue
-		}
+type targeter interface {
+	Targets(zbcontext.Context, dependency.TargetType) ([]*dependency.Target, error)
+}
 
-		pkgs.Insert(pkg)
+// generate runs go generate, when asked to with --generate, for the stale
+// zb:generate targets, including those in test files, so that the tests, and
+// whether their results are cached, reflect the generated files
+func (co *cc) generate(ctx zbcontext.Context, t targeter) error {
+	if !co.Generate || ctx.NoGenerate || co.List {
+		return nil
+	}
 
-		var foundResult bool
-		if foundResult, err = co.HaveResult(ctx, pkg); err != nil {
-			return
-		}
+	ctx.GenerateTests = true
 
-		if !foundResult {
-			toRun.Insert(pkg)
-		}
+	targets, err := t.Targets(ctx, dependency.TargetGenerate)
+	if err != nil {
+		return err
 	}
 
-	return
+	_, err = dependency.Build(ctx, dependency.TargetGenerate, targets)
+	return err
 }
 
-func (co *cc) buildProjectsLists(ctx zbcontext.Context, projects project.List) (pkgs, toRun project.Packages, err error) {
-	for _, proj := range projects {
-		var p, r project.Packages
-		p, r, err = co.buildPackagesLists(ctx, proj.Packages)
-		if err != nil {
-			return
-		}
-
-		pkgs = pkgs.Append(p)
-		toRun = toRun.Append(r)
-	}
+func (co *cc) buildPackagesLists(ctx zbcontext.Context, in project.Packages) (pkgs, toRun project.Packages, err error) {
+	return zbtest.PackagesLists(ctx, co, in, nil)
+}
 
-	return
+func (co *cc) buildProjectsLists(ctx zbcontext.Context, projects project.List) (pkgs, toRun project.Packages, err error) {
+	return zbtest.ProjectsLists(ctx, co, projects, nil)
 }
 
 func (co *cc) runTest(ctx zbcontext.Context, w io.Writer, pkgs, toRun project.Packages) error {
-	var ecmd *exec.Cmd
-	pr, pw := io.Pipe()
-	if len(toRun) > 0 {
-		if err := os.MkdirAll(ctx.CacheDir, 0700); err != nil {
-			return err
-		}
-
-		args := []string{"test"}
-		args = append(args, co.TestArgs(nil, nil)...)
-		for _, pkg := range toRun {
-			args = append(args, pkg.ImportPath)
-		}
-
-		ctx.Logger.Debug(zbcontext.QuoteCommand("→ go", args))
+	_, code, err := co.execTest(ctx, w, pkgs, toRun)
+	if err != nil {
+		return err
+	}
 
-		ecmd = exec.Command("go", args...) // nosec
-		ecmd.Stdout = pw
-		ecmd.Stderr = pw
-		if err := ecmd.Start(); err != nil {
-			return err
-		}
+	if code != zbcontext.ExitOK {
+		return cli.NewExitError("", code)
 	}
 
-	code := zbcontext.ExitOK
-	var group errgroup.Group
-	group.Go(func() error {
-		defer func() { _ = pw.Close() }() // nosec
+	return nil
+}
+
+// execTest runs go test on the toRun packages and shows the cached results of
+// the rest. It returns the status of each package keyed by import path along
+// with the exit code.
+func (co *cc) execTest(ctx zbcontext.Context, w io.Writer, pkgs, toRun project.Packages) (map[string]*zbtest.Status, int, error) {
+	statuses := map[string]*zbtest.Status{}
+	var cachedFailure bool
 
-		if ecmd == nil {
+	code, err := zbtest.Exec(ctx, w, co.RunArgs(), co.Env(), pkgs, toRun, func(pkg *project.Package, r zbtest.StringReader) error {
+		if r != nil {
+			status, err := co.ReadResult(ctx, r, pkg)
+			if err != nil {
+				return err
+			}
+			if status != nil {
+				statuses[pkg.ImportPath] = status
+			}
 			return nil
 		}
 
-		ecode, err := zbcontext.ExitCode(ecmd.Wait())
+		status, err := co.ShowResult(ctx, w, pkg)
 		if err != nil {
 			return err
 		}
-
-		if code == zbcontext.ExitOK {
-			code = ecode
+		statuses[pkg.ImportPath] = status
+		if !status.Passed() {
+			cachedFailure = true
 		}
-
 		return nil
 	})
 
-	r := bufio.NewReader(io.TeeReader(pr, w))
-
-	for _, pkg := range pkgs {
-		if len(toRun) > 0 && toRun[0] == pkg {
-			if err := co.ReadResult(ctx, r, pkg); err != nil {
-				return err
-			}
-			toRun = toRun[1:]
-		} else {
-			passed, err := co.ShowResult(ctx, w, pkg)
-			if err != nil {
-				return err
-			}
-			if code == zbcontext.ExitOK && !passed {
-				code = zbcontext.ExitFailed
-			}
-		}
+	if err != nil {
+		return nil, code, err
 	}
 
-	if _, err := io.Copy(w, r); err != nil {
-		return err
+	if code == zbcontext.ExitOK && cachedFailure {
+		code = zbcontext.ExitFailed
 	}
 
-	if err := group.Wait(); err != nil {
-		return err
+	if err = co.SaveHistory(ctx); err != nil {
+		return nil, code, err
 	}
 
-	if code != zbcontext.ExitOK {
-		return cli.NewExitError("", code)
-	}
-
-	return nil
+	return statuses, code, nil
 }
@@ -40,6 +40,10 @@ type Data struct {
 	Tags          stringsFlag
 	ToolExec      stringsFlag
 	GenerateRun   string
+	J             int
+	GenerateJobs  int
+	CompileJobs   int
+	K             bool
 
 	context *build.Context
 }
@@ -152,6 +156,18 @@ func (f *Data) RebuildAll() bool {
 	return f.A
 }
 
+// JobLimits returns the maximum number of targets, and of each kind of target,
+// that zb builds concurrently
+func (f *Data) JobLimits() (jobs, generate, compile int) {
+	return f.J, f.GenerateJobs, f.CompileJobs
+}
+
+// KeepGoing reports whether zb continues to build the targets that don't
+// depend on ones that failed
+func (f *Data) KeepGoing() bool {
+	return f.K
+}
+
 // BuildContext returns a build context based on environment variables GOARCH,
 // GOOS, GOROOT, GOPATH, CGO_ENABLED and command line flags
 func (f *Data) BuildContext() *build.Context {
@@ -341,6 +357,37 @@ func (f *Data) BuildFlags(run bool) []cli.Flag {
 				(excluding any trailing spaces and final newline) matches the
 				expression.`,
 			Destination: &f.GenerateRun,
+		}, cli.IntFlag{
+			Name:        "j",
+			Value:       defaultP,
+			Destination: &f.J,
+			Usage: `
+
+				the number of go generate, go build and go install commands
+				that zb runs in parallel. The default is the number of CPUs
+				available.`,
+		}, cli.IntFlag{
+			Name:        "generate-jobs",
+			Destination: &f.GenerateJobs,
+			Usage: `
+
+				the number of go generate commands that zb runs in parallel, up
+				to -j. The default is -j.`,
+		}, cli.IntFlag{
+			Name:        "compile-jobs",
+			Destination: &f.CompileJobs,
+			Usage: `
+
+				the number of go build and go install commands that zb runs in
+				parallel, up to -j. The default is -j.`,
+		}, cli.BoolFlag{
+			Name:        "k, keep-going",
+			Destination: &f.K,
+			Usage: `
+
+				continue after a target fails, building everything that doesn't
+				depend on it, and print a summary of the failed targets and of
+				those that were skipped because of them.`,
 		})
 	}
 
@@ -45,3 +45,30 @@ func (g *Graph) dfs(node *node, finishList *[]Node) {
 	}
 	*finishList = append(*finishList, node.container)
 }
+
+// LongestPaths returns, for each node of a directed acyclic graph, the length
+// of the longest path that starts at it, where the length of a path is the
+// sum of the weights of its nodes (including the first). weight is called
+// once for each node.
+//
+// If the graph is cyclic, the lengths are not meaningful.
+func (g *Graph) LongestPaths(weight func(Node) int64) map[Node]int64 {
+	sorted := g.TopologicalSort()
+	ret := make(map[Node]int64, len(sorted))
+
+	// every node is after all of the nodes it has edges to when reversed
+	for i := len(sorted) - 1; i >= 0; i-- {
+		n := sorted[i]
+
+		var max int64
+		for _, edge := range n.node.edges {
+			if l := ret[edge.end.container]; l > max {
+				max = l
+			}
+		}
+
+		ret[n] = weight(n) + max
+	}
+
+	return ret
+}
@@ -82,3 +82,37 @@ func setupTopologicalSort() (*Graph, []Node) {
 	wantOrder[8] = nodes[2] // jacket
 	return graph, wantOrder
 }
+
+func TestLongestPaths(t *testing.T) {
+	graph, _ := setupTopologicalSort()
+
+	// weigh each node by its position in the graph plus one so that the
+	// longest path isn't simply the one with the most nodes
+	weights := map[Node]int64{}
+	for i, n := range graph.nodes {
+		weights[n.container] = int64(i + 1)
+	}
+
+	paths := graph.LongestPaths(func(n Node) int64 {
+		return weights[n]
+	})
+
+	nodes := graph.nodes
+	want := []int64{
+		1 + 4 + 3, // shirt → belt → jacket (vs shirt → tie → jacket = 1+2+3)
+		2 + 3,     // tie → jacket
+		3,         // jacket
+		4 + 3,     // belt → jacket
+		5,         // watch
+		6 + 7 + 8, // undershorts → pants → shoes
+		7 + 8,     // pants → shoes (vs pants → belt → jacket = 7+4+3)
+		8,         // shoes
+		9 + 8,     // socks → shoes
+	}
+
+	for i, w := range want {
+		if got := paths[nodes[i].container]; got != w {
+			t.Errorf("longest path from node %d = %d, want %d", i, got, w)
+		}
+	}
+}
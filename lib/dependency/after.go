@@ -0,0 +1,72 @@
+package dependency
+
+import (
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// after is a package whose zb:generate directives must be generated before
+// those of a directive with -after. The go files of the package are inputs of
+// the directive.
+type after struct {
+	importPath string
+	dir        string
+	files      []string
+}
+
+func (e *GoFile) resolveAfter(ctx zbcontext.Context, importPaths []string) ([]*after, error) {
+	var ret []*after
+
+	for _, importPath := range importPaths {
+		p, err := ctx.Import(importPath, filepath.Dir(e.Path))
+
+		// the package may not have any go files until it is generated
+		if _, ok := err.(*build.NoGoError); !ok && err != nil {
+			return nil, errors.Wrapf(err, "could not find -after package %s", importPath)
+		}
+
+		a := after{
+			importPath: p.ImportPath,
+			dir:        p.Dir,
+		}
+
+		for _, f := range p.GoFiles {
+			a.files = append(a.files, filepath.Join(p.Dir, f))
+		}
+
+		for _, f := range p.CgoFiles {
+			a.files = append(a.files, filepath.Join(p.Dir, f))
+		}
+
+		ret = append(ret, &a)
+	}
+
+	return ret, nil
+}
+
+// hash returns the import path of the package and the hashes of its go files
+// for inclusion in the hash of a directive
+func (a *after) hash() (string, error) {
+	files := make([]string, len(a.files))
+	copy(files, a.files)
+	sort.Strings(files)
+
+	ret := fmt.Sprintf("after %s\n", a.importPath)
+
+	for _, file := range files {
+		fh, err := hashFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		ret += fmt.Sprintf("%s\x00%s\n", filepath.Base(file), fh)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,196 @@
+package dependency
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Problem is an issue found with a zb:generate directive
+type Problem struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", relPath(p.Path), p.Line, p.Message)
+}
+
+type directiveChecker struct {
+	problems []Problem
+	outputs  map[string]*directive // the directive that generates each file
+}
+
+// CheckDirectives parses the zb:generate directives of the go files and
+// returns the problems found with them in the order of the files and lines.
+// Unlike building, it doesn't stop at the first problem.
+func CheckDirectives(files []string) ([]Problem, error) {
+	sorted := make([]string, len(files))
+	copy(sorted, files)
+	sort.Strings(sorted)
+
+	c := directiveChecker{outputs: map[string]*directive{}}
+
+	for _, path := range sorted {
+		if err := c.checkFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.problems, nil
+}
+
+func (c *directiveChecker) add(d *directive, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{
+		Path:    d.path,
+		Line:    d.line,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *directiveChecker) checkFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+
+	var goGenerate bool
+	for _, line := range lines {
+		if isGoGenerate([]byte(line)) {
+			goGenerate = true
+			break
+		}
+	}
+
+	for i, line := range lines {
+		if !isZBGenerate([]byte(line)) {
+			continue
+		}
+
+		d := directive{
+			path: path,
+			line: i + 1,
+			text: strings.TrimSpace(line),
+		}
+
+		if !goGenerate {
+			c.add(&d, "no //go:generate directive in %s", filepath.Base(path))
+		}
+
+		c.checkDirective(&d, line)
+	}
+
+	return nil
+}
+
+func (c *directiveChecker) checkDirective(d *directive, line string) {
+	if !strings.HasSuffix(line, "\n") {
+		c.add(d, "directive is not followed by a newline")
+		return
+	}
+
+	words, err := split(line)
+	if err != nil {
+		c.add(d, "%s", err)
+		return
+	}
+
+	if _, words, err = parseOptions(words); err != nil {
+		c.add(d, "%s", err)
+		return
+	}
+
+	if len(words) == 0 {
+		c.add(d, "no arguments to directive")
+		return
+	}
+
+	// the globs of -patsubst and -target are inputs, otherwise they are the
+	// generated files which may not exist yet
+	var patterns []string
+	inputs := true
+
+	switch words[0] {
+	case "-patsubst":
+		if len(words) < 4 {
+			c.add(d, "invalid patsubst, expected -patsubst pattern replacement glob...")
+			return
+		}
+		patterns = words[3:]
+	case "-target":
+		if len(words) < 3 {
+			c.add(d, "invalid target, expected -target file glob...")
+			return
+		}
+		patterns = words[2:]
+	default:
+		patterns = words
+		inputs = false
+	}
+
+	e := &GoFile{Path: d.path}
+
+	var matched []string
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
+			continue
+		}
+
+		files, err := e.parseGlobs([]string{pattern})
+		if err != nil {
+			c.add(d, "%s", err)
+			return
+		}
+
+		if inputs {
+			files = existing(files)
+		}
+
+		if len(files) == 0 {
+			c.add(d, "%q matches no files", pattern)
+		}
+
+		matched = append(matched, files...)
+	}
+
+	deps, err := e.parseZBGenerate(words)
+	if err != nil {
+		c.add(d, "%s", err)
+		return
+	}
+
+	if words[0] == "-patsubst" && len(matched) > 0 && len(deps) == 0 {
+		c.add(d, "patsubst pattern %q matches none of the files", words[1])
+	}
+
+	seen := map[string]bool{}
+	for _, dep := range deps {
+		if seen[dep.Path] {
+			continue
+		}
+		seen[dep.Path] = true
+
+		if other, ok := c.outputs[dep.Path]; ok {
+			c.add(d, "%s is also generated by %s", relPath(dep.Path), other)
+			continue
+		}
+
+		c.outputs[dep.Path] = d
+	}
+}
+
+func existing(files []string) []string {
+	var ret []string
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			ret = append(ret, file)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,61 @@
+package dependency
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckDirectives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbdirectives")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	files := map[string]string{
+		"a.proto": "",
+		"a.go": `package a
+
+//go:generate protoc *.proto
+//zb:generate -patsubst %.proto %.pb.go *.proto
+//zb:generate -patsubst %.txt %.pb.go *.proto
+//zb:generate -target out.go *.json
+//zb:generate "unbalanced
+//zb:generate -patsubst %.proto
+//zb:generate a.pb.go
+`,
+		"b.go": `package a
+
+//zb:generate b_gen.go
+`,
+	}
+
+	for name, data := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+
+	problems, err := CheckDirectives([]string{b, a})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Problem{
+		{a, 5, `patsubst pattern "%.txt" matches none of the files`},
+		{a, 6, `"*.json" matches no files`},
+		{a, 7, "mismatched quoted string"},
+		{a, 8, "invalid patsubst, expected -patsubst pattern replacement glob..."},
+		{a, 9, relPath(filepath.Join(dir, "a.pb.go")) + " is also generated by " + relPath(a) + ":4"},
+		{b, 3, "no //go:generate directive in b.go"},
+	}
+
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("CheckDirectives() = %q, want %q", problems, want)
+	}
+}
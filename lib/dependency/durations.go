@@ -0,0 +1,202 @@
+package dependency
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"jrubin.io/zb/lib/dag"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// durations are how long each target took the last time it was built. They
+// are stored in the cache directory and used to prioritize targets.
+type durations struct {
+	Targets map[string]time.Duration `json:"targets"`
+
+	file  string
+	mu    sync.Mutex
+	dirty bool
+}
+
+var (
+	buildDurations   *durations
+	buildDurationsMu sync.Mutex
+)
+
+func durationsFile(ctx zbcontext.Context) string {
+	if ctx.CacheDir == "" {
+		return ""
+	}
+	return filepath.Join(ctx.CacheDir, "build", "durations.json")
+}
+
+// loadDurations returns the recorded durations, reading them from the cache
+// directory the first time
+func loadDurations(ctx zbcontext.Context) *durations {
+	buildDurationsMu.Lock()
+	defer buildDurationsMu.Unlock()
+
+	if buildDurations != nil {
+		return buildDurations
+	}
+
+	d := durations{
+		Targets: map[string]time.Duration{},
+		file:    durationsFile(ctx),
+	}
+
+	buildDurations = &d
+
+	if d.file == "" {
+		return &d
+	}
+
+	data, err := ioutil.ReadFile(d.file)
+	if os.IsNotExist(err) {
+		return &d
+	}
+
+	var read durations
+	if err == nil {
+		err = json.Unmarshal(data, &read)
+	}
+
+	if err != nil {
+		ctx.Logger.WithError(err).Warn("discarding unreadable build durations")
+		return &d
+	}
+
+	if read.Targets != nil {
+		d.Targets = read.Targets
+	}
+
+	return &d
+}
+
+func (d *durations) get(t *Target) (time.Duration, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dur, ok := d.Targets[t.key()]
+	return dur, ok
+}
+
+func (d *durations) record(t *Target, dur time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.Targets[t.key()] = dur
+	d.dirty = true
+}
+
+func (d *durations) save() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.dirty || d.file == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(d.file), 0700); err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(d.file, data, 0600); err != nil {
+		return err
+	}
+
+	d.dirty = false
+	return nil
+}
+
+// priority orders the targets that are ready to be built
+type priority struct {
+	// path is the sum of the durations of the targets on the longest chain
+	// of targets that depend on this one, including itself
+	path int64
+
+	// fanOut is the number of targets that depend directly on this one
+	fanOut int
+}
+
+func (p priority) higher(o priority) bool {
+	if p.path != o.path {
+		return p.path > o.path
+	}
+	return p.fanOut > o.fanOut
+}
+
+// priorities returns the priority of each of the targets. Targets on the
+// critical path, the longest chain of dependent targets weighted by their
+// recorded durations, come first. Buildable targets without a recorded
+// duration are assumed to take the average time. Without any recorded
+// durations, targets that more targets depend on come first.
+func priorities(targets []*Target, d *durations) map[*Target]priority {
+	graph := dag.Graph{}
+	nodes := make(map[*Target]dag.Node, len(targets))
+
+	for _, t := range targets {
+		nodes[t] = graph.MakeNode(t)
+	}
+
+	ret := make(map[*Target]priority, len(targets))
+
+	var total time.Duration
+	var known int
+
+	for _, t := range targets {
+		t.RequiredBy.Range(func(r *Target) {
+			if n, ok := nodes[r]; ok {
+				if err := graph.MakeEdge(nodes[t], n); err != nil {
+					panic(err)
+				}
+
+				p := ret[t]
+				p.fanOut++
+				ret[t] = p
+			}
+		})
+
+		if dur, ok := d.get(t); ok && t.Buildable() {
+			total += dur
+			known++
+		}
+	}
+
+	if known == 0 {
+		return ret
+	}
+
+	average := total / time.Duration(known)
+
+	paths := graph.LongestPaths(func(n dag.Node) int64 {
+		t := (*n.Value).(*Target)
+
+		if !t.Buildable() {
+			return 0
+		}
+
+		if dur, ok := d.get(t); ok {
+			return int64(dur)
+		}
+
+		return int64(average)
+	})
+
+	for t, n := range nodes {
+		p := ret[t]
+		p.path = paths[n]
+		ret[t] = p
+	}
+
+	return ret
+}
@@ -0,0 +1,173 @@
+package dependency
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"jrubin.io/slog"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// keepGoer is implemented by BuildArgers that continue to build the targets
+// that don't depend on ones that failed
+type keepGoer interface {
+	KeepGoing() bool
+}
+
+func keepGoing(ctx zbcontext.Context) bool {
+	k, ok := ctx.BuildArger.(keepGoer)
+	return ok && k.KeepGoing()
+}
+
+// Failure is a target that failed to build
+type Failure struct {
+	*Target
+	Err error
+
+	// Skipped are the buildable targets that weren't built because they
+	// depend on the target
+	Skipped []*Target
+}
+
+// Command returns the command that failed
+func (f *Failure) Command() string {
+	if gerr, ok := errors.Cause(f.Err).(*zbcontext.GoError); ok {
+		return gerr.Command()
+	}
+	return describe(f.Target)
+}
+
+// Output returns the output of the command that failed
+func (f *Failure) Output() string {
+	if gerr, ok := errors.Cause(f.Err).(*zbcontext.GoError); ok {
+		return gerr.Output
+	}
+	return f.Err.Error()
+}
+
+// Failures is the error returned by Each when it keeps going after targets
+// fail
+type Failures []*Failure
+
+func (f Failures) Error() string {
+	if len(f) == 1 {
+		return fmt.Sprintf("%s failed", describe(f[0].Target))
+	}
+	return fmt.Sprintf("%d targets failed", len(f))
+}
+
+// log writes the summary of the failures
+func (f Failures) log(ctx zbcontext.Context, tt TargetType) {
+	var skipped int
+	for _, failure := range f {
+		for _, s := range failure.Skipped {
+			if included(tt, s) {
+				skipped++
+			}
+		}
+	}
+
+	ctx.Logger.Error(fmt.Sprintf("%s, %d skipped", f, skipped))
+
+	for _, failure := range f {
+		ctx.Logger.Error("failed: " + failure.Command())
+
+		w := ctx.Logger.Writer(slog.ErrorLevel).Prefix("← ")
+		_, _ = io.WriteString(w, failure.Output()) // nosec
+		_ = w.Close()                              // nosec
+
+		for _, s := range failure.Skipped {
+			if included(tt, s) {
+				ctx.Logger.Warn(fmt.Sprintf("skipped: %s (depends on %s)", describe(s), describe(failure.Target)))
+			}
+		}
+	}
+}
+
+// failureSet tracks the targets that failed while keeping going and the
+// targets that depend on them
+type failureSet struct {
+	mu        sync.Mutex
+	blockedBy map[*Target]*Failure
+	failures  Failures
+}
+
+// block marks the targets that require t as depending on the failure, s.mu
+// must be held
+func (s *failureSet) block(t *Target, f *Failure) {
+	if s.blockedBy == nil {
+		s.blockedBy = map[*Target]*Failure{}
+	}
+
+	t.RequiredBy.Range(func(r *Target) {
+		if _, ok := s.blockedBy[r]; !ok {
+			s.blockedBy[r] = f
+		}
+	})
+}
+
+func (s *failureSet) fail(t *Target, err error) {
+	s.mu.Lock()
+	f := &Failure{Target: t, Err: err}
+	s.failures = append(s.failures, f)
+	s.block(t, f)
+	s.mu.Unlock()
+}
+
+// skip reports whether the target depends on one that failed, in which case
+// the targets that require it are skipped too
+func (s *failureSet) skip(t *Target) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.blockedBy[t]
+	if !ok {
+		return false
+	}
+
+	if t.Buildable() {
+		f.Skipped = append(f.Skipped, t)
+	}
+
+	s.block(t, f)
+	return true
+}
+
+// err returns the failures, sorted by target, or nil if there weren't any
+func (s *failureSet) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.failures) == 0 {
+		return nil
+	}
+
+	byName := func(ts []*Target) func(i, j int) bool {
+		return func(i, j int) bool {
+			return describe(ts[i]) < describe(ts[j])
+		}
+	}
+
+	for _, f := range s.failures {
+		sort.Slice(f.Skipped, byName(f.Skipped))
+	}
+
+	sort.Slice(s.failures, func(i, j int) bool {
+		return describe(s.failures[i].Target) < describe(s.failures[j].Target)
+	})
+
+	return s.failures
+}
+
+// describe returns the import path of package targets and the relative path
+// of the others
+func describe(t *Target) string {
+	if pkg, ok := t.Dependency.(*GoPackage); ok {
+		return pkg.ImportPath
+	}
+	return relPath(t.Name())
+}
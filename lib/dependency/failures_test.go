@@ -0,0 +1,75 @@
+package dependency
+
+import (
+	"go/build"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"srcd.works/go-git.v4/plumbing"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+type testKeepGoing bool
+
+func (k testKeepGoing) BuildArgs(*build.Package, *plumbing.Hash) []string { return nil }
+func (k testKeepGoing) RebuildAll() bool                                  { return false }
+func (k testKeepGoing) KeepGoing() bool                                   { return bool(k) }
+
+func TestEachKeepGoing(t *testing.T) {
+	targets := map[string]*Target{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		targets[name] = &Target{Dependency: testCompile(name)}
+	}
+
+	// a ← b ← c, d ← e and a fails
+	require := func(dep, t string) {
+		targets[dep].RequiredBy.Insert(targets[t])
+		targets[t].Add(1)
+		targets[dep].OnDone(targets[t].WaitGroup.Done)
+	}
+	require("a", "b")
+	require("b", "c")
+	require("d", "e")
+
+	list := []*Target{targets["a"], targets["b"], targets["c"], targets["d"], targets["e"]}
+
+	var mu sync.Mutex
+	ran := map[string]bool{}
+
+	err := Each(zbcontext.Context{BuildArger: testKeepGoing(true)}, list, func(target *Target) error {
+		mu.Lock()
+		ran[target.Name()] = true
+		mu.Unlock()
+
+		if target.Name() == "a" {
+			return errors.New("a failed")
+		}
+		return nil
+	})
+
+	failures, ok := err.(Failures)
+	if !ok || len(failures) != 1 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if f := failures[0]; f.Target != targets["a"] || f.Output() != "a failed" {
+		t.Errorf("unexpected failure of %s: %v", f.Name(), f.Err)
+	}
+
+	var skipped []string
+	for _, s := range failures[0].Skipped {
+		skipped = append(skipped, s.Name())
+	}
+
+	if len(skipped) != 2 || skipped[0] != "b" || skipped[1] != "c" {
+		t.Errorf("skipped %v, want [b c]", skipped)
+	}
+
+	for name, want := range map[string]bool{"a": true, "b": false, "c": false, "d": true, "e": true} {
+		if ran[name] != want {
+			t.Errorf("ran %s = %v, want %v", name, ran[name], want)
+		}
+	}
+}
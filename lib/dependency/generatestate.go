@@ -0,0 +1,262 @@
+package dependency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// GenerateStateFile is the name of the file, in the root of each project,
+// that records the hash of the inputs of the zb:generate directive each
+// generated file was last generated from. It is meant to be committed so that
+// fresh checkouts don't regenerate files whose inputs haven't changed.
+const GenerateStateFile = ".zbgenerate.json"
+
+const generateStateVersion = 1
+
+// generateState is the content of a GenerateStateFile
+type generateState struct {
+	Version int               `json:"version"`
+	Outputs map[string]string `json:"outputs"` // hashes by project relative path
+
+	dir   string
+	mu    sync.Mutex
+	dirty bool
+}
+
+var (
+	generateStates   = map[string]*generateState{}
+	generateStatesMu sync.Mutex
+)
+
+// loadGenerateState returns the state of the project in dir, reading it from
+// the GenerateStateFile the first time
+func loadGenerateState(dir string) (*generateState, error) {
+	generateStatesMu.Lock()
+	defer generateStatesMu.Unlock()
+
+	if s, ok := generateStates[dir]; ok {
+		return s, nil
+	}
+
+	s := &generateState{
+		Version: generateStateVersion,
+		Outputs: map[string]string{},
+		dir:     dir,
+	}
+
+	file := filepath.Join(dir, GenerateStateFile)
+
+	data, err := ioutil.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		var read generateState
+		if err = json.Unmarshal(data, &read); err != nil {
+			return nil, errors.Wrapf(err, "error reading %s", file)
+		}
+
+		// state from other versions is discarded and rebuilt
+		if read.Version == generateStateVersion && read.Outputs != nil {
+			s.Outputs = read.Outputs
+		}
+	}
+
+	generateStates[dir] = s
+	return s, nil
+}
+
+func (s *generateState) key(path string) string {
+	if rel, err := filepath.Rel(s.dir, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+func (s *generateState) get(path string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash, ok := s.Outputs[s.key(path)]
+	return hash, ok
+}
+
+func (s *generateState) set(path, hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := s.key(path)
+	if s.Outputs[key] != hash {
+		s.Outputs[key] = hash
+		s.dirty = true
+	}
+}
+
+func (s *generateState) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(s.dir, GenerateStateFile), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	s.dirty = false
+	return nil
+}
+
+// SaveGenerateState writes the GenerateStateFile of each project whose
+// recorded hashes have changed
+func SaveGenerateState() error {
+	generateStatesMu.Lock()
+	defer generateStatesMu.Unlock()
+
+	for _, s := range generateStates {
+		if err := s.save(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type fileHash struct {
+	modTime time.Time
+	size    int64
+	hash    string
+}
+
+var (
+	fileHashes   = map[string]fileHash{}
+	fileHashesMu sync.Mutex
+)
+
+// hashFile returns the sha256 of the content of the file. Hashes are cached
+// until the file's modification time or size changes.
+func hashFile(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	fileHashesMu.Lock()
+	fh, ok := fileHashes[path]
+	fileHashesMu.Unlock()
+
+	if ok && fh.modTime.Equal(fi.ModTime()) && fh.size == fi.Size() {
+		return fh.hash, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }() // nosec
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	fh = fileHash{
+		modTime: fi.ModTime(),
+		size:    fi.Size(),
+		hash:    hex.EncodeToString(h.Sum(nil)),
+	}
+
+	fileHashesMu.Lock()
+	fileHashes[path] = fh
+	fileHashesMu.Unlock()
+
+	return fh.hash, nil
+}
+
+// directive is a single zb:generate annotation
+type directive struct {
+	path   string // of the go file
+	line   int
+	text   string
+	inputs []string
+	tools  []*tool
+	after  []*after
+}
+
+func (d *directive) String() string {
+	return fmt.Sprintf("%s:%d", relPath(d.path), d.line)
+}
+
+// hash returns the hash of the text of the directive, the paths, relative to
+// dir, and contents of its inputs and the files of its tools and -after
+// packages
+func (d *directive) hash(dir string) (string, error) {
+	inputs := make([]string, len(d.inputs))
+	copy(inputs, d.inputs)
+	sort.Strings(inputs)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", d.text)
+
+	for _, input := range inputs {
+		fh, err := hashFile(input)
+		if err != nil {
+			return "", err
+		}
+
+		rel, err := filepath.Rel(dir, input)
+		if err != nil {
+			rel = input
+		}
+
+		fmt.Fprintf(h, "%s\x00%s\n", filepath.ToSlash(rel), fh)
+	}
+
+	for _, t := range d.tools {
+		th, err := t.hash()
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprint(h, th)
+	}
+
+	for _, a := range d.after {
+		ah, err := a.hash()
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprint(h, ah)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// projectDir returns the root of the git repository containing the go file
+func (d *directive) projectDir() string {
+	return zbcontext.GitDir(filepath.Dir(d.path))
+}
@@ -0,0 +1,291 @@
+package dependency
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// expandBraces expands the alternatives in braces, e.g. *.{proto,json}
+// becomes *.proto and *.json. Braces may be nested and escaped with \.
+func expandBraces(pattern string) []string {
+	start, depth := -1, 0
+
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+
+			depth--
+			if depth > 0 {
+				continue
+			}
+
+			prefix, suffix := pattern[:start], pattern[i+1:]
+
+			var ret []string
+			for _, alt := range splitAlternatives(pattern[start+1 : i]) {
+				ret = append(ret, expandBraces(prefix+alt+suffix)...)
+			}
+
+			return ret
+		}
+	}
+
+	return []string{pattern}
+}
+
+// splitAlternatives splits the content of braces on the commas that are not
+// within nested braces
+func splitAlternatives(value string) []string {
+	var ret []string
+	var depth, last int
+
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				ret = append(ret, value[last:i])
+				last = i + 1
+			}
+		}
+	}
+
+	return append(ret, value[last:])
+}
+
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// globMatch reports whether the slash separated name matches the pattern. A
+// ** path segment matches zero or more path segments, other segments are
+// matched with path.Match.
+func globMatch(pattern, name string) (bool, error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true, nil
+			}
+
+			for i := 0; i <= len(name); i++ {
+				if ok, err := matchSegments(pattern, name[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false, err
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0, nil
+}
+
+// validGlob returns an error if any segment of the pattern is malformed
+func validGlob(pattern string) error {
+	for _, s := range strings.Split(pattern, "/") {
+		if _, err := path.Match(s, ""); err != nil {
+			return errors.Wrapf(err, "invalid glob %q", pattern)
+		}
+	}
+	return nil
+}
+
+// walkGlob returns the files, relative to dir, that match the slash separated
+// pattern. Patterns without a ** segment are expanded with filepath.Glob.
+// Otherwise only the directories below the part of the pattern without meta
+// characters are walked and, like when looking for packages, directories
+// beginning with . or _ and testdata and vendor directories are skipped.
+func walkGlob(dir, pattern string) ([]string, error) {
+	segments := strings.Split(pattern, "/")
+
+	var recursive bool
+	for _, s := range segments {
+		if s == "**" {
+			recursive = true
+			break
+		}
+	}
+
+	if !recursive {
+		return fileGlob(dir, pattern)
+	}
+
+	var static []string
+	for _, s := range segments {
+		if hasMeta(s) {
+			break
+		}
+		static = append(static, s)
+	}
+
+	root := filepath.Join(dir, filepath.FromSlash(strings.Join(static, "/")))
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var ret []string
+
+	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			if p != root && skipDir(fi.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		ok, err := globMatch(pattern, rel)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			ret = append(ret, rel)
+		}
+
+		return nil
+	})
+
+	sort.Strings(ret)
+
+	return ret, err
+}
+
+// skipDir reports whether the directory is skipped when walking for a **
+// pattern
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") ||
+		strings.HasPrefix(name, "_") ||
+		name == "testdata" ||
+		name == "vendor"
+}
+
+// fileGlob returns the files, relative to dir, that match the slash separated
+// pattern, which must not contain **
+func fileGlob(dir, pattern string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid glob %q", pattern)
+	}
+
+	var ret []string
+
+	for _, match := range matches {
+		fi, err := os.Stat(match)
+		if err != nil || fi.IsDir() {
+			continue
+		}
+
+		rel, err := filepath.Rel(dir, match)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, filepath.ToSlash(rel))
+	}
+
+	sort.Strings(ret)
+
+	return ret, nil
+}
+
+// glob expands the patterns, relative to dir, into file paths. Patterns
+// support ** and braces, those beginning with ! exclude the files they match.
+// Patterns without meta characters are returned as is, whether or not the
+// file exists.
+func glob(dir string, patterns []string) ([]string, error) {
+	var include, exclude []string
+
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "!") {
+			exclude = append(exclude, expandBraces(filepath.ToSlash(p[1:]))...)
+			continue
+		}
+
+		for _, e := range expandBraces(filepath.ToSlash(p)) {
+			include = append(include, path.Clean(e))
+		}
+	}
+
+	for _, p := range append(include, exclude...) {
+		if err := validGlob(p); err != nil {
+			return nil, err
+		}
+	}
+
+	var ret []string
+	seen := map[string]bool{}
+
+	for _, p := range include {
+		matches := []string{p}
+
+		if hasMeta(p) {
+			var err error
+			if matches, err = walkGlob(dir, p); err != nil {
+				return nil, err
+			}
+		}
+
+	MATCHES:
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+
+			for _, e := range exclude {
+				if ok, _ := globMatch(path.Clean(e), match); ok {
+					continue MATCHES
+				}
+			}
+
+			seen[match] = true
+			ret = append(ret, filepath.Join(dir, filepath.FromSlash(match)))
+		}
+	}
+
+	return ret, nil
+}
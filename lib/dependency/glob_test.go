@@ -0,0 +1,142 @@
+package dependency
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.go", []string{"*.go"}},
+		{"*.{proto,json}", []string{"*.proto", "*.json"}},
+		{"{a,b}/{c,d}", []string{"a/c", "a/d", "b/c", "b/d"}},
+		{"x.{a,{b,c}}", []string{"x.a", "x.b", "x.c"}},
+		{`\{a,b}`, []string{`\{a,b}`}},
+	}
+
+	for _, test := range tests {
+		if got := expandBraces(test.pattern); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("expandBraces(%q) = %q, want %q", test.pattern, got, test.want)
+		}
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.proto", "a.proto", true},
+		{"*.proto", "x/a.proto", false},
+		{"**/*.proto", "a.proto", true},
+		{"**/*.proto", "x/y/a.proto", true},
+		{"x/**/a.proto", "x/a.proto", true},
+		{"x/**/a.proto", "x/y/z/a.proto", true},
+		{"x/**/a.proto", "y/a.proto", false},
+		{"x/**", "x/y/z", true},
+		{"x/*", "x/y/z", false},
+	}
+
+	for _, test := range tests {
+		got, err := globMatch(test.pattern, test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != test.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbglob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	files := []string{
+		"a.proto",
+		"a.json",
+		"x/b.proto",
+		"x/y/c.proto",
+		"x/y/c_test.proto",
+		".hidden/d.proto",
+		"_skip/e.proto",
+		"testdata/f.proto",
+		"vendor/g.proto",
+		"x/testdata/h.proto",
+		"x/y.proto/i.go",
+	}
+
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		patterns []string
+		want     []string
+	}{
+		{[]string{"*.proto"}, []string{"a.proto"}},
+		{[]string{"*.{proto,json}"}, []string{"a.proto", "a.json"}},
+		{[]string{"**/*.proto"}, []string{"a.proto", "x/b.proto", "x/y/c.proto", "x/y/c_test.proto"}},
+		{[]string{"**/*.proto", "!**/*_test.proto", "!x/b.proto"}, []string{"a.proto", "x/y/c.proto"}},
+		{[]string{"./x/**/*.proto", "x/b.proto"}, []string{"x/b.proto", "x/y/c.proto", "x/y/c_test.proto"}},
+		{[]string{"testdata/**/*.proto"}, []string{"testdata/f.proto"}},
+		{[]string{"*/*.proto"}, []string{".hidden/d.proto", "_skip/e.proto", "testdata/f.proto", "vendor/g.proto", "x/b.proto"}},
+		{[]string{"x/*.proto"}, []string{"x/b.proto"}},
+		{[]string{"missing.go"}, []string{"missing.go"}},
+		{[]string{"nothing/**/*.go"}, nil},
+	}
+
+	for _, test := range tests {
+		got, err := glob(dir, test.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want []string
+		for _, w := range test.want {
+			want = append(want, filepath.Join(dir, filepath.FromSlash(w)))
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("glob(%q) = %q, want %q", test.patterns, got, want)
+		}
+	}
+
+	if _, err = glob(dir, []string{"[.proto"}); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	tests := []struct {
+		pattern, replacement, file, want string
+	}{
+		{"%.proto", "%.pb.go", "message.proto", "message.pb.go"},
+		{"%.proto", "%.pb.go", "x/y/message.proto", "x/y/message.pb.go"},
+		{"proto/%.proto", "gen/%.pb.go", "proto/x/message.proto", "gen/x/message.pb.go"},
+		{"proto/%.proto", "gen/%.pb.go", "other/message.proto", ""},
+		{"%", "out.go", "in.txt", "out.go"},
+	}
+
+	for _, test := range tests {
+		if got := substitute(test.pattern, test.replacement, test.file); got != test.want {
+			t.Errorf("substitute(%q, %q, %q) = %q, want %q", test.pattern, test.replacement, test.file, got, test.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/pkg/errors"
 	"jrubin.io/slog"
+	"jrubin.io/zb/lib/generated"
 	"jrubin.io/zb/lib/zbcontext"
 )
 
@@ -76,6 +78,11 @@ func isZBGenerate(buf []byte) bool {
 		bytes.HasPrefix(buf, []byte("//zb:generate\t"))
 }
 
+func isGoGenerate(buf []byte) bool {
+	return bytes.HasPrefix(buf, []byte("//go:generate ")) ||
+		bytes.HasPrefix(buf, []byte("//go:generate\t"))
+}
+
 func isTodoOrFixme(buf []byte) bool {
 	return bytes.Contains(buf, []byte(strings.ToUpper("todo"))) ||
 		bytes.Contains(buf, []byte(strings.ToUpper("fixme")))
@@ -110,6 +117,9 @@ func (e *GoFile) Dependencies(ctx zbcontext.Context) ([]Dependency, error) {
 	}
 	defer func() { _ = file.Close() }() // nosec
 
+	// generated files are not expected to be edited by hand
+	warnTodoFixme := !ctx.NoWarnTodoFixme && !generated.File(e.Path)
+
 	// the following loop taken largely from go source src/cmd/go/generate.go
 
 	// Scan for lines that start "//zb:generate".
@@ -123,7 +133,7 @@ func (e *GoFile) Dependencies(ctx zbcontext.Context) ([]Dependency, error) {
 		if err == bufio.ErrBufferFull {
 			// Line too long - consume and ignore.
 			if isZBGenerate(buf) {
-				return nil, errors.Errorf("zb:generate directive too long")
+				return nil, errors.Errorf("%s:%d: zb:generate directive too long", relPath(e.Path), i)
 			}
 			for err == bufio.ErrBufferFull {
 				_, err = input.ReadSlice('\n')
@@ -142,7 +152,7 @@ func (e *GoFile) Dependencies(ctx zbcontext.Context) ([]Dependency, error) {
 			break
 		}
 
-		if !ctx.NoWarnTodoFixme {
+		if warnTodoFixme {
 			base := ctx.ImportPathToDir(e.ProjectImportPath) + string(filepath.Separator)
 
 			if strings.HasPrefix(e.Path, base) && !strings.Contains(e.Path, "vendor/") && isTodoOrFixme(buf) {
@@ -160,23 +170,55 @@ func (e *GoFile) Dependencies(ctx zbcontext.Context) ([]Dependency, error) {
 			continue
 		}
 
+		// errors in directives include their position
+		pos := fmt.Sprintf("%s:%d", relPath(e.Path), i)
+
 		var words []string
 		words, err = split(string(buf))
 		if err != nil {
-			return nil, err
+			return nil, errors.Wrap(err, pos)
+		}
+
+		var opts *directiveOptions
+		opts, words, err = parseOptions(words)
+		if err != nil {
+			return nil, errors.Wrap(err, pos)
 		}
 
 		if len(words) == 0 {
-			return nil, errors.New("no arguments to directive")
+			return nil, errors.Errorf("%s: no arguments to directive", pos)
 		}
 
 		var deps []*GoGenerateFile
 		deps, err = e.parseZBGenerate(words)
 		if err != nil {
-			return nil, err
+			return nil, errors.Wrap(err, pos)
+		}
+
+		d := directive{
+			path: e.Path,
+			line: i,
+			text: strings.TrimSpace(string(buf)),
+		}
+
+		if d.tools, err = e.resolveTools(ctx, opts.tools); err != nil {
+			return nil, errors.Wrap(err, pos)
+		}
+
+		if d.after, err = e.resolveAfter(ctx, opts.after); err != nil {
+			return nil, errors.Wrap(err, pos)
 		}
 
+		seen := map[string]bool{}
 		for _, dep := range deps {
+			if !seen[dep.Depends.Name()] {
+				seen[dep.Depends.Name()] = true
+				d.inputs = append(d.inputs, dep.Depends.Name())
+			}
+		}
+
+		for _, dep := range deps {
+			dep.directive = &d
 			var source string
 			source, err = filepath.Rel(zbcontext.CWD, dep.Path)
 			if err != nil {
@@ -262,26 +304,42 @@ Words:
 	return words, nil
 }
 
-func (e *GoFile) parseGlobs(words []string) ([]string, error) {
-	var files []string
+// directiveOptions are the options that may precede the arguments of a
+// directive
+type directiveOptions struct {
+	tools []string // -tool name
+	after []string // -after importpath
+}
 
-	for _, word := range words {
-		word = filepath.Join(filepath.Dir(e.Path), word)
+// parseOptions removes the leading options from the words of a directive
+func parseOptions(words []string) (*directiveOptions, []string, error) {
+	var opts directiveOptions
 
-		if !strings.Contains(word, "*") {
-			files = append(files, word)
-			continue
+	for len(words) > 0 {
+		var dst *[]string
+
+		switch words[0] {
+		case "-tool":
+			dst = &opts.tools
+		case "-after":
+			dst = &opts.after
+		default:
+			return &opts, words, nil
 		}
 
-		matches, err := filepath.Glob(word)
-		if err != nil {
-			return nil, err
+		if len(words) < 2 {
+			return nil, nil, errors.Errorf("invalid %s", words[0][1:])
 		}
 
-		files = append(files, matches...)
+		*dst = append(*dst, words[1])
+		words = words[2:]
 	}
 
-	return files, nil
+	return &opts, words, nil
+}
+
+func (e *GoFile) parseGlobs(words []string) ([]string, error) {
+	return glob(filepath.Dir(e.Path), words)
 }
 
 func (e *GoFile) parseZBGenerate(words []string) ([]*GoGenerateFile, error) {
@@ -355,20 +413,34 @@ func (e *GoFile) parsePatSubst(words []string) ([]*GoGenerateFile, error) {
 		return nil, err
 	}
 
+	dir := filepath.Dir(e.Path)
+
 	var deps []*GoGenerateFile
 	for _, file := range files {
-		sfile := substitute(pattern, replacement, file)
-		if !filepath.IsAbs(sfile) {
-			sfile = filepath.Join(filepath.Dir(e.Path), sfile)
+		// patterns are matched against paths relative to the go file, like
+		// the globs, unless they are absolute
+		name := file
+		if !filepath.IsAbs(pattern) {
+			if rel, err := filepath.Rel(dir, file); err == nil {
+				name = filepath.ToSlash(rel)
+			}
+		}
+
+		sfile := substitute(pattern, replacement, name)
+		if sfile == "" {
+			continue
 		}
-		if sfile != "" {
-			// run go generate on `e.Path` if `file` is newer than `sfile`
-			deps = append(deps, &GoGenerateFile{
-				GoFile:  e,
-				Depends: File(file),
-				Path:    sfile,
-			})
+
+		if !filepath.IsAbs(sfile) {
+			sfile = filepath.Join(dir, filepath.FromSlash(sfile))
 		}
+
+		// run go generate on `e.Path` if `file` is newer than `sfile`
+		deps = append(deps, &GoGenerateFile{
+			GoFile:  e,
+			Depends: File(file),
+			Path:    sfile,
+		})
 	}
 
 	return deps, nil
@@ -441,6 +513,42 @@ func substitute(pattern, replacement, file string) string {
 	return ret
 }
 
+// Unmanaged returns the go files, of the targets in the projects being built,
+// that have a generated code header but that are not generated by any
+// zb:generate directive. Vendored files are ignored.
+func Unmanaged(ctx zbcontext.Context, targets []*Target) []string {
+	outputs := map[string]bool{}
+	var files []*GoFile
+
+	for _, target := range targets {
+		switch dep := target.Dependency.(type) {
+		case *GoGenerateFile:
+			outputs[dep.Path] = true
+		case *GoFile:
+			files = append(files, dep)
+		}
+	}
+
+	var ret []string
+
+	for _, f := range files {
+		base := ctx.ImportPathToDir(f.ProjectImportPath) + string(filepath.Separator)
+
+		if outputs[f.Path] ||
+			!strings.HasPrefix(f.Path, base) ||
+			strings.Contains(f.Path, "vendor/") ||
+			!generated.File(f.Path) {
+			continue
+		}
+
+		ret = append(ret, f.Path)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
 func (e *GoFile) Buildable() bool {
 	return false
 }
@@ -460,6 +568,24 @@ func (e *GoFile) Generate(ctx zbcontext.Context) error {
 	args = append(args, e.BuildArgs...)
 	args = append(args, e.Path)
 
-	err := ctx.GoExec(args...)
-	return err
+	if err := ctx.GoExec(args...); err != nil {
+		return err
+	}
+
+	// with -n, go generate only prints the commands
+	for _, arg := range e.BuildArgs {
+		if arg == "-n" {
+			return nil
+		}
+	}
+
+	for _, dep := range e.dependencies {
+		if gen, ok := dep.(*GoGenerateFile); ok {
+			if err := gen.generated(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
@@ -1,6 +1,7 @@
 package dependency
 
 import (
+	"fmt"
 	"os"
 	"time"
 
@@ -13,6 +14,8 @@ type GoGenerateFile struct {
 	GoFile  *GoFile
 	Depends File
 	Path    string
+
+	directive *directive
 }
 
 func (f GoGenerateFile) Name() string {
@@ -28,13 +31,97 @@ func (f GoGenerateFile) Build(ctx zbcontext.Context) error {
 	f.GoFile.mu.Lock()
 	defer f.GoFile.mu.Unlock()
 
-	if !ctx.RebuildAll() && !f.Depends.ModTime().After(f.ModTime()) {
-		return nil
+	if !ctx.RebuildAll() {
+		if _, stale, err := f.stale(ctx); err != nil || !stale {
+			return err
+		}
 	}
 
 	return f.GoFile.Generate(ctx)
 }
 
+// stale reports whether the file needs to be generated. When the project
+// records the hash of the inputs the file was generated from, the file is
+// stale only if that hash has changed. Otherwise modification times are
+// compared. Hashes are only recorded once the file has been generated since a
+// file that is newer than its inputs wasn't necessarily generated from them.
+func (f GoGenerateFile) stale(ctx zbcontext.Context) (string, bool, error) {
+	if f.ModTime().IsZero() {
+		return fmt.Sprintf("%s does not exist", relPath(f.Path)), true, nil
+	}
+
+	var newer bool
+	var reason string
+
+	deps, err := f.Dependencies(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	for _, dep := range deps {
+		if dep.ModTime().After(f.ModTime()) {
+			newer = true
+			reason = fmt.Sprintf("%s is newer than %s", relPath(dep.Name()), relPath(f.Path))
+			break
+		}
+	}
+
+	var dir string
+	if f.directive != nil {
+		dir = f.directive.projectDir()
+	}
+
+	if dir == "" {
+		return reason, newer, nil
+	}
+
+	state, err := loadGenerateState(dir)
+	if err != nil {
+		return "", false, err
+	}
+
+	hash, err := f.directive.hash(dir)
+	if err != nil {
+		return "", false, err
+	}
+
+	recorded, ok := state.get(f.Path)
+	if !ok {
+		return reason, newer, nil
+	}
+
+	if recorded != hash {
+		return fmt.Sprintf("inputs of %s changed", f.directive), true, nil
+	}
+
+	return "", false, nil
+}
+
+// generated records the hash of the inputs the file was generated from
+func (f GoGenerateFile) generated() error {
+	if f.directive == nil || f.ModTime().IsZero() {
+		return nil
+	}
+
+	dir := f.directive.projectDir()
+	if dir == "" {
+		return nil
+	}
+
+	state, err := loadGenerateState(dir)
+	if err != nil {
+		return err
+	}
+
+	hash, err := f.directive.hash(dir)
+	if err != nil {
+		return err
+	}
+
+	state.set(f.Path, hash)
+	return nil
+}
+
 func (f GoGenerateFile) Install(ctx zbcontext.Context) error {
 	return f.Build(ctx)
 }
@@ -48,7 +135,21 @@ func (f GoGenerateFile) ModTime() time.Time {
 }
 
 func (f GoGenerateFile) Dependencies(zbcontext.Context) ([]Dependency, error) {
-	return []Dependency{f.Depends}, nil
+	deps := []Dependency{f.Depends}
+
+	if f.directive != nil {
+		for _, t := range f.directive.tools {
+			deps = append(deps, t.dep)
+		}
+
+		for _, a := range f.directive.after {
+			for _, file := range a.files {
+				deps = append(deps, File(file))
+			}
+		}
+	}
+
+	return deps, nil
 }
 
 func (f GoGenerateFile) Buildable() bool {
@@ -0,0 +1,103 @@
+package dependency
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+func TestGoGenerateFileStale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbgenerate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	if err = os.Mkdir(filepath.Join(dir, ".git"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	goFile := filepath.Join(dir, "a.go")
+	input := filepath.Join(dir, "a.proto")
+	output := filepath.Join(dir, "a.pb.go")
+
+	write := func(path, data string, modTime time.Time) {
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	write(goFile, "package a\n\n//zb:generate a.pb.go a.proto\n", now.Add(-2*time.Hour))
+	write(input, "message A {}\n", now.Add(-time.Hour))
+	write(output, "package a\n", now)
+
+	f := GoGenerateFile{
+		GoFile:  &GoFile{Path: goFile},
+		Depends: File(input),
+		Path:    output,
+		directive: &directive{
+			path:   goFile,
+			line:   3,
+			text:   "a.pb.go a.proto",
+			inputs: []string{input},
+		},
+	}
+
+	state, err := loadGenerateState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := zbcontext.Context{}
+
+	// the output is newer than its input but wasn't necessarily generated
+	// from it, so no hash is recorded
+	if _, stale, err := f.stale(ctx); err != nil || stale {
+		t.Fatalf("stale = %v, %v, want false", stale, err)
+	}
+
+	if _, ok := state.get(output); ok {
+		t.Error("hash recorded without generating")
+	}
+
+	// the input changes but keeps an older modification time
+	write(input, "message B {}\n", now.Add(-time.Hour))
+
+	if _, stale, err := f.stale(ctx); err != nil || stale {
+		t.Fatalf("stale by modification time = %v, %v, want false", stale, err)
+	}
+
+	if err = f.generated(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := state.get(output); !ok {
+		t.Fatal("hash not recorded after generating")
+	}
+
+	if _, stale, err := f.stale(ctx); err != nil || stale {
+		t.Fatalf("stale after generating = %v, %v, want false", stale, err)
+	}
+
+	// once a hash is recorded, a changed input makes the output stale even
+	// though it is older
+	write(input, "message Changed {}\n", now.Add(-time.Hour))
+
+	reason, stale, err := f.stale(ctx)
+	if err != nil || !stale {
+		t.Fatalf("stale with changed inputs = %v, %v, want true", stale, err)
+	}
+
+	if want := "inputs of " + f.directive.String() + " changed"; reason != want {
+		t.Errorf("reason = %q, want %q", reason, want)
+	}
+}
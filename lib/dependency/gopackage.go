@@ -18,6 +18,10 @@ type GoPackage struct {
 	Path              string
 	ProjectImportPath string
 
+	// Tool is set for commands that are zb:generate tools, they are always
+	// installed
+	Tool bool
+
 	dependencies []Dependency
 }
 
@@ -28,7 +32,7 @@ func (pkg *GoPackage) Name() string {
 }
 
 func (pkg *GoPackage) Build(ctx zbcontext.Context) error {
-	if !pkg.IsCommand() {
+	if !pkg.IsCommand() || pkg.Tool {
 		return pkg.Install(ctx)
 	}
 
@@ -88,8 +92,11 @@ func (pkg *GoPackage) files(ctx zbcontext.Context) []Dependency {
 	files = append(files, pkg.SwigFiles...)
 	files = append(files, pkg.SwigCXXFiles...)
 	files = append(files, pkg.SysoFiles...)
-	// files = append(files, pkg.TestGoFiles...)
-	// files = append(files, pkg.XTestGoFiles...)
+
+	if ctx.GenerateTests {
+		files = append(files, pkg.TestGoFiles...)
+		files = append(files, pkg.XTestGoFiles...)
+	}
 
 	gofiles := make([]Dependency, len(files))
 	for i, f := range files {
@@ -0,0 +1,143 @@
+package dependency
+
+import (
+	"runtime"
+	"sync"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// jobClass is the kind of work that building a target involves, each class
+// has its own limit on the number of concurrent jobs
+type jobClass int
+
+const (
+	compileJob jobClass = iota
+	generateJob
+)
+
+func classOf(t *Target) jobClass {
+	if _, ok := t.Dependency.(*GoGenerateFile); ok {
+		return generateJob
+	}
+	return compileJob
+}
+
+// jobLimiter is implemented by BuildArgers that limit the number of targets
+// that are built concurrently. Limits that are not positive default to the
+// number of CPUs for jobs and to jobs for the others.
+type jobLimiter interface {
+	JobLimits() (jobs, generate, compile int)
+}
+
+type job struct {
+	class    jobClass
+	priority priority
+	start    chan struct{}
+}
+
+// scheduler runs ready targets, highest priority first and otherwise in the
+// order they became ready, as long as neither the total number of running
+// jobs nor the number of running jobs of their class is at its limit. Targets
+// that can't run because their class is at its limit don't hold up those of
+// the other class.
+type scheduler struct {
+	mu         sync.Mutex
+	queue      []*job
+	running    int
+	byClass    map[jobClass]int
+	limit      int
+	limits     map[jobClass]int
+	priorities map[*Target]priority
+}
+
+func newScheduler(ctx zbcontext.Context, targets []*Target) *scheduler {
+	jobs, generate, compile := runtime.NumCPU(), 0, 0
+
+	if l, ok := ctx.BuildArger.(jobLimiter); ok {
+		var j int
+		if j, generate, compile = l.JobLimits(); j > 0 {
+			jobs = j
+		}
+	}
+
+	limit := func(n int) int {
+		if n <= 0 || n > jobs {
+			return jobs
+		}
+		return n
+	}
+
+	return &scheduler{
+		byClass: map[jobClass]int{},
+		limit:   jobs,
+		limits: map[jobClass]int{
+			compileJob:  limit(compile),
+			generateJob: limit(generate),
+		},
+		priorities: priorities(targets, loadDurations(ctx)),
+	}
+}
+
+// wait queues the target and blocks until it may be built. done must be
+// called with the returned class once it has been.
+func (s *scheduler) wait(t *Target) jobClass {
+	j := &job{
+		class:    classOf(t),
+		priority: s.priorities[t],
+		start:    make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.queue = append(s.queue, j)
+	s.dispatch()
+	s.mu.Unlock()
+
+	<-j.start
+
+	return j.class
+}
+
+func (s *scheduler) done(class jobClass) {
+	s.mu.Lock()
+	s.running--
+	s.byClass[class]--
+	s.dispatch()
+	s.mu.Unlock()
+}
+
+// next returns the index of the queued job that should be started next, or -1
+// if none can be
+func (s *scheduler) next() int {
+	if s.running >= s.limit {
+		return -1
+	}
+
+	next := -1
+
+	for i, j := range s.queue {
+		if s.byClass[j.class] >= s.limits[j.class] {
+			continue
+		}
+
+		if next == -1 || j.priority.higher(s.queue[next].priority) {
+			next = i
+		}
+	}
+
+	return next
+}
+
+// dispatch starts the queued jobs that are within the limits, s.mu must be
+// held
+func (s *scheduler) dispatch() {
+	for i := s.next(); i != -1; i = s.next() {
+		j := s.queue[i]
+
+		s.queue = append(s.queue[:i], s.queue[i+1:]...)
+		s.running++
+		s.byClass[j.class]++
+
+		close(j.start)
+	}
+}
@@ -0,0 +1,135 @@
+package dependency
+
+import (
+	"fmt"
+	"go/build"
+	"sync"
+	"testing"
+	"time"
+
+	"srcd.works/go-git.v4/plumbing"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+type testLimits struct {
+	jobs, generate, compile int
+}
+
+func (l testLimits) BuildArgs(*build.Package, *plumbing.Hash) []string { return nil }
+func (l testLimits) RebuildAll() bool                                  { return false }
+func (l testLimits) JobLimits() (int, int, int)                        { return l.jobs, l.generate, l.compile }
+
+type testCompile string
+
+func (c testCompile) Name() string                                         { return string(c) }
+func (c testCompile) Build(zbcontext.Context) error                        { return nil }
+func (c testCompile) Install(zbcontext.Context) error                      { return nil }
+func (c testCompile) ModTime() time.Time                                   { return time.Time{} }
+func (c testCompile) Dependencies(zbcontext.Context) ([]Dependency, error) { return nil, nil }
+func (c testCompile) Buildable() bool                                      { return true }
+
+func TestEachLimits(t *testing.T) {
+	var targets []*Target
+	for i := 0; i < 8; i++ {
+		targets = append(targets,
+			&Target{Dependency: &GoGenerateFile{Path: fmt.Sprintf("gen%d", i)}},
+			&Target{Dependency: testCompile(fmt.Sprintf("pkg%d", i))},
+		)
+	}
+
+	ctx := zbcontext.Context{BuildArger: testLimits{jobs: 3, generate: 1}}
+
+	var mu sync.Mutex
+	var running, maxRunning, generating, maxGenerating, ran int
+
+	err := Each(ctx, targets, func(target *Target) error {
+		_, gen := target.Dependency.(*GoGenerateFile)
+
+		mu.Lock()
+		ran++
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		if gen {
+			generating++
+			if generating > maxGenerating {
+				maxGenerating = generating
+			}
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		if gen {
+			generating--
+		}
+		mu.Unlock()
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ran != len(targets) {
+		t.Errorf("ran %d targets, want %d", ran, len(targets))
+	}
+
+	if maxRunning != 3 {
+		t.Errorf("max concurrent jobs = %d, want 3", maxRunning)
+	}
+
+	if maxGenerating != 1 {
+		t.Errorf("max concurrent generate jobs = %d, want 1", maxGenerating)
+	}
+}
+
+func TestPriorities(t *testing.T) {
+	targets := map[string]*Target{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		targets[name] = &Target{Dependency: testCompile(name)}
+	}
+
+	// a ← b ← c, a ← d and e alone
+	targets["a"].RequiredBy.Insert(targets["b"])
+	targets["b"].RequiredBy.Insert(targets["c"])
+	targets["a"].RequiredBy.Insert(targets["d"])
+
+	list := []*Target{targets["a"], targets["b"], targets["c"], targets["d"], targets["e"]}
+
+	// without history, fan out decides
+	d := &durations{Targets: map[string]time.Duration{}}
+	p := priorities(list, d)
+
+	if !p[targets["a"]].higher(p[targets["b"]]) || !p[targets["b"]].higher(p[targets["e"]]) {
+		t.Errorf("unexpected priorities without history: %v", p)
+	}
+
+	// c has no recorded duration so it is assumed to take the average of the
+	// others, 7s/4
+	d.Targets[targets["a"].key()] = time.Second
+	d.Targets[targets["b"].key()] = time.Second
+	d.Targets[targets["d"].key()] = time.Second
+	d.Targets[targets["e"].key()] = 4 * time.Second
+
+	p = priorities(list, d)
+
+	want := map[string]int64{
+		"a": int64(1*time.Second + 1*time.Second + 7*time.Second/4),
+		"b": int64(1*time.Second + 7*time.Second/4),
+		"c": int64(7 * time.Second / 4),
+		"d": int64(time.Second),
+		"e": int64(4 * time.Second),
+	}
+
+	for name, w := range want {
+		if got := p[targets[name]].path; got != w {
+			t.Errorf("critical path of %s = %v, want %v", name, time.Duration(got), time.Duration(w))
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package dependency
 
 import (
-	"errors"
+	"fmt"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
 	"golang.org/x/sync/errgroup"
 
 	"jrubin.io/zb/lib/dag"
@@ -168,14 +173,124 @@ func (ts *Targets) Append(r *Targets) {
 	ts.mu.Unlock()
 }
 
+// requires reports whether the target depends, directly or indirectly, on dep
+func (t *Target) requires(dep *Target) bool {
+	seen := map[*Target]bool{}
+	queue := []*Target{dep}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		if cur == t {
+			return true
+		}
+
+		if seen[cur] {
+			continue
+		}
+		seen[cur] = true
+
+		cur.RequiredBy.Range(func(r *Target) {
+			queue = append(queue, r)
+		})
+	}
+
+	return false
+}
+
+// LinkGenerators adds the dependencies between zb:generate targets that
+// aren't found by following the dependencies of the packages. A generated
+// file is required by the generated files whose inputs include it, and the
+// generated files of a package are required by those of directives that are
+// -after it. An error is returned if the directives depend on each other.
+func (ts *Targets) LinkGenerators() error {
+	var gens []*Target
+
+	ts.Range(func(t *Target) {
+		if _, ok := t.Dependency.(*GoGenerateFile); ok {
+			gens = append(gens, t)
+		}
+	})
+
+	sort.Slice(gens, func(i, j int) bool {
+		return gens[i].Name() < gens[j].Name()
+	})
+
+	outputs := map[string]*Target{}
+	byDir := map[string][]*Target{}
+
+	for _, t := range gens {
+		gen := t.Dependency.(*GoGenerateFile)
+		outputs[gen.Path] = t
+
+		dir := filepath.Dir(gen.GoFile.Path)
+		byDir[dir] = append(byDir[dir], t)
+	}
+
+	for _, t := range gens {
+		gen := t.Dependency.(*GoGenerateFile)
+
+		var required []*Target
+
+		if o, ok := outputs[gen.Depends.Name()]; ok {
+			required = append(required, o)
+		}
+
+		if gen.directive != nil {
+			for _, a := range gen.directive.after {
+				required = append(required, byDir[a.dir]...)
+			}
+		}
+
+		for _, r := range required {
+			rgen := r.Dependency.(*GoGenerateFile)
+
+			// files generated by the same go file are generated together
+			if rgen.GoFile == gen.GoFile {
+				continue
+			}
+
+			if r.requires(t) {
+				return errors.Errorf("zb:generate cycle: %s and %s depend on each other", rgen.directive, gen.directive)
+			}
+
+			r.RequiredBy.Insert(t)
+		}
+	}
+
+	return nil
+}
+
 type TargetFunc func(*Target) error
 
+// Each calls fn for each of the buildable targets once the targets it depends
+// on are done. The number of concurrent calls is limited by a scheduler that
+// starts the targets on the critical path first. Once fn returns an error no
+// more targets are started unless the BuildArger keeps going, in which case
+// only the targets that depend on the failed one are skipped and Failures is
+// returned.
 func Each(ctx zbcontext.Context, targets []*Target, fn TargetFunc) error {
 	var group errgroup.Group
 
 	var cancel uint32
 
-	for _, t := range targets {
+	var failed *failureSet
+	if keepGoing(ctx) {
+		failed = &failureSet{}
+	}
+
+	sched := newScheduler(ctx, targets)
+
+	// start the targets in priority order so that those that are ready at
+	// once are queued roughly in that order too
+	ordered := make([]*Target, len(targets))
+	copy(ordered, targets)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return sched.priorities[ordered[i]].higher(sched.priorities[ordered[j]])
+	})
+
+	for _, t := range ordered {
 		target := t
 
 		deps, err := target.Dependencies(ctx)
@@ -186,7 +301,12 @@ func Each(ctx zbcontext.Context, targets []*Target, fn TargetFunc) error {
 		group.Go(func() (err error) {
 			defer func() {
 				if err != nil {
-					atomic.StoreUint32(&cancel, 1)
+					if failed != nil {
+						failed.fail(target, err)
+						err = nil
+					} else {
+						atomic.StoreUint32(&cancel, 1)
+					}
 				}
 				target.Done()
 			}()
@@ -201,16 +321,32 @@ func Each(ctx zbcontext.Context, targets []*Target, fn TargetFunc) error {
 				return
 			}
 
+			if failed != nil && failed.skip(target) {
+				return
+			}
+
 			if !target.Buildable() {
 				return
 			}
 
+			class := sched.wait(target)
+			defer sched.done(class)
+
+			// another target may have failed while this one was queued
+			if atomic.LoadUint32(&cancel) == 1 {
+				return
+			}
+
 			err = fn(target)
 			return
 		})
 	}
 
-	return group.Wait()
+	if err := group.Wait(); err != nil || failed == nil {
+		return err
+	}
+
+	return failed.err()
 }
 
 type TargetType int
@@ -233,44 +369,185 @@ func (tt TargetType) String() string {
 	return ""
 }
 
+// staler is implemented by dependencies that determine whether they need to
+// be built themselves rather than by comparing modification times with their
+// dependencies
+type staler interface {
+	stale(ctx zbcontext.Context) (reason string, stale bool, err error)
+}
+
+// staleness returns why the target needs to be built, or nil if it doesn't.
+// All targets with dependencies are stale when everything is being rebuilt.
+func staleness(ctx zbcontext.Context, target *Target) (*Stale, error) {
+	deps, err := target.Dependencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.RebuildAll() && len(deps) > 0 {
+		return &Stale{Target: target}, nil
+	}
+
+	if s, ok := target.Dependency.(staler); ok {
+		reason, stale, err := s.stale(ctx)
+		if err != nil || !stale {
+			return nil, err
+		}
+
+		return &Stale{Target: target, reason: reason}, nil
+	}
+
+	// build target if any of its dependencies are newer than itself
+	for _, dep := range deps {
+		// don't use .Before since filesystem time resolution might
+		// cause files times to be within the same second
+		if dep.ModTime().After(target.ModTime()) {
+			return &Stale{Target: target, Newer: dep}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// included reports whether targets of the type are built for tt
+func included(tt TargetType, target *Target) bool {
+	if tt == TargetGenerate {
+		// exclude all dependencies that aren't go generate files or the
+		// tools they use
+		switch dep := target.Dependency.(type) {
+		case *GoGenerateFile:
+			return true
+		case *GoPackage:
+			return dep.Tool
+		}
+		return false
+	}
+
+	return true
+}
+
 func Build(ctx zbcontext.Context, tt TargetType, targets []*Target) (int, error) {
 	var built uint32
+
+	durs := loadDurations(ctx)
+	record := !dryRun(ctx)
+
 	err := Each(ctx, targets, func(target *Target) error {
-		if tt == TargetGenerate {
-			if _, ok := target.Dependency.(*GoGenerateFile); !ok {
-				// exclude all dependencies that aren't go generate files
-				return nil
-			}
+		if !included(tt, target) {
+			return nil
 		}
 
-		deps, err := target.Dependencies(ctx)
-		if err != nil {
+		stale, err := staleness(ctx, target)
+		if err != nil || stale == nil {
 			return err
 		}
 
-		// build target if any of its dependencies are newer than itself
-		for _, dep := range deps {
-			// don't use .Before since filesystem time resolution might
-			// cause files times to be within the same second
-			if !ctx.RebuildAll() && !dep.ModTime().After(target.ModTime()) {
-				continue
-			}
+		start := time.Now()
 
-			if tt == TargetInstall {
-				err = target.Install(ctx)
-			} else {
-				err = target.Build(ctx)
-			}
+		if tt == TargetInstall {
+			err = target.Install(ctx)
+		} else {
+			err = target.Build(ctx)
+		}
 
-			if err != nil {
-				return err
-			}
+		if err != nil {
+			return err
+		}
 
-			atomic.AddUint32(&built, 1)
-			return nil
+		if record {
+			durs.record(target, time.Since(start))
 		}
 
+		atomic.AddUint32(&built, 1)
 		return nil
 	})
+
+	if failures, ok := err.(Failures); ok {
+		failures.log(ctx, tt)
+		err = cli.NewExitError("", zbcontext.ExitFailed)
+	}
+
+	// record the hashes of what was generated even if the build failed
+	if serr := SaveGenerateState(); err == nil {
+		err = serr
+	}
+
+	if serr := durs.save(); err == nil {
+		err = serr
+	}
+
 	return int(built), err
 }
+
+// Stale is a target that needs to be built
+type Stale struct {
+	*Target
+
+	// Newer is the dependency that is newer than the target, it is nil when
+	// the target is stale only because everything is being rebuilt or it
+	// determined that it was stale itself
+	Newer Dependency
+
+	reason string
+}
+
+// dryRun reports whether the go commands only print what they would do
+func dryRun(ctx zbcontext.Context) bool {
+	if ctx.BuildArger == nil {
+		return false
+	}
+
+	for _, arg := range ctx.BuildArgs(nil, nil) {
+		if arg == "-n" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func relPath(path string) string {
+	if rel, err := filepath.Rel(zbcontext.CWD, path); err == nil && len(rel) < len(path) {
+		return rel
+	}
+	return path
+}
+
+// Reason describes why the target is stale
+func (s Stale) Reason() string {
+	switch {
+	case s.reason != "":
+		return s.reason
+	case s.Newer == nil:
+		return "forced by -a"
+	case s.ModTime().IsZero():
+		return fmt.Sprintf("%s does not exist", relPath(s.Name()))
+	}
+
+	return fmt.Sprintf("%s is newer than %s", relPath(s.Newer.Name()), relPath(s.Name()))
+}
+
+// StaleTargets returns, in dependency order, the targets of type tt that Build
+// would build. Staleness is determined from the current state of the files so
+// targets that would only become stale once their dependencies are built are
+// not included.
+func StaleTargets(ctx zbcontext.Context, tt TargetType, targets []*Target) ([]Stale, error) {
+	var ret []Stale
+
+	for _, target := range targets {
+		if !target.Buildable() || !included(tt, target) {
+			continue
+		}
+
+		stale, err := staleness(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+
+		if stale != nil {
+			ret = append(ret, *stale)
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,120 @@
+package dependency
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"jrubin.io/slog"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// tool is a generator that a zb:generate directive depends on
+type tool struct {
+	name string
+	dep  Dependency
+
+	// files are hashed to detect changes to the tool. they are the go files
+	// of the tool's package. tools in the PATH have none, their binaries
+	// differ between machines so only their names are hashed.
+	files []string
+}
+
+// resolveTool finds the named tool. Names containing a / are import paths of
+// commands in the GOPATH, others are looked up in the PATH. Commands in the
+// same repository as the go file are built, as a dependency, before the files
+// are generated. The binaries of tools in the PATH are only dependencies by
+// modification time. Tools that can't be found are logged and ignored since go
+// generate will fail if they are needed.
+func (e *GoFile) resolveTool(ctx zbcontext.Context, name string) (*tool, error) {
+	logger := ctx.Logger.WithFields(slog.Fields{
+		"tool":    name,
+		"go_file": relPath(e.Path),
+	})
+
+	if !strings.Contains(name, "/") {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			logger.WithError(err).Warn("could not find zb:generate tool")
+			return nil, nil
+		}
+
+		return &tool{
+			name: name,
+			dep:  File(path),
+		}, nil
+	}
+
+	dir := filepath.Dir(e.Path)
+
+	p, err := ctx.Import(name, dir)
+	if err != nil {
+		logger.WithError(err).Warn("could not find zb:generate tool")
+		return nil, nil
+	}
+
+	if !p.IsCommand() {
+		return nil, errors.Errorf("zb:generate tool %s is not a command", name)
+	}
+
+	t := tool{name: p.ImportPath}
+	for _, f := range p.GoFiles {
+		t.files = append(t.files, filepath.Join(p.Dir, f))
+	}
+
+	repo := zbcontext.GitDir(dir)
+	if repo != "" && zbcontext.GitDir(p.Dir) == repo {
+		t.dep = &GoPackage{
+			ProjectImportPath: e.ProjectImportPath,
+			Path:              zbcontext.InstallPath(p),
+			Package:           p,
+			Tool:              true,
+		}
+	} else {
+		t.dep = File(zbcontext.InstallPath(p))
+	}
+
+	return &t, nil
+}
+
+func (e *GoFile) resolveTools(ctx zbcontext.Context, names []string) ([]*tool, error) {
+	var ret []*tool
+
+	for _, name := range names {
+		t, err := e.resolveTool(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if t != nil {
+			ret = append(ret, t)
+		}
+	}
+
+	return ret, nil
+}
+
+// hash returns the name of the tool and the hashes of its files for inclusion
+// in the hash of a directive
+func (t *tool) hash() (string, error) {
+	files := make([]string, len(t.files))
+	copy(files, t.files)
+	sort.Strings(files)
+
+	ret := fmt.Sprintf("tool %s\n", t.name)
+
+	for _, file := range files {
+		fh, err := hashFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		ret += fmt.Sprintf("%s\x00%s\n", filepath.Base(file), fh)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,42 @@
+package dependency
+
+import (
+	"os/exec"
+	"testing"
+
+	"jrubin.io/slog"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+func TestPathToolHash(t *testing.T) {
+	path, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not installed")
+	}
+
+	e := &GoFile{Path: "a.go"}
+
+	tl, err := e.resolveTool(zbcontext.Context{Logger: slog.New()}, "sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tl == nil {
+		t.Fatal("sh was not found")
+	}
+
+	if tl.dep.Name() != path {
+		t.Errorf("dependency = %s, want %s", tl.dep.Name(), path)
+	}
+
+	// the binary differs between machines so it must not be part of the
+	// hash that is recorded in the project
+	h, err := tl.hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "tool sh\n"; h != want {
+		t.Errorf("hash = %q, want %q", h, want)
+	}
+}
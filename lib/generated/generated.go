@@ -0,0 +1,92 @@
+// Package generated detects go source files that were generated by a tool
+// using the convention described at https://golang.org/s/generatedcode
+package generated
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var headerRE = regexp.MustCompile(`\A// Code generated .* DO NOT EDIT\.\z`)
+
+// IsHeader reports whether the line is a generated code header
+func IsHeader(line string) bool {
+	return headerRE.MatchString(line)
+}
+
+// Reader reports whether the go source read from r has a generated code
+// header. The header must appear before the package clause.
+func Reader(r io.Reader) (bool, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for s.Scan() {
+		line := bytes.TrimRight(s.Bytes(), "\r")
+
+		if IsHeader(string(line)) {
+			return true, nil
+		}
+
+		if bytes.HasPrefix(line, []byte("package ")) {
+			return false, nil
+		}
+	}
+
+	return false, s.Err()
+}
+
+// Source reports whether the go source has a generated code header
+func Source(src []byte) bool {
+	ok, _ := Reader(bytes.NewReader(src))
+	return ok
+}
+
+type result struct {
+	modTime   time.Time
+	generated bool
+}
+
+var (
+	cache   = map[string]result{}
+	cacheMu sync.Mutex
+)
+
+// File reports whether the go file has a generated code header. Results are
+// cached until the file is modified. Files that can't be read are not
+// generated.
+func File(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	cacheMu.Lock()
+	r, ok := cache[path]
+	cacheMu.Unlock()
+
+	if ok && r.modTime.Equal(fi.ModTime()) {
+		return r.generated
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }() // nosec
+
+	r = result{modTime: fi.ModTime()}
+	if r.generated, err = Reader(f); err != nil {
+		return false
+	}
+
+	cacheMu.Lock()
+	cache[path] = r
+	cacheMu.Unlock()
+
+	return r.generated
+}
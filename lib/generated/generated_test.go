@@ -0,0 +1,23 @@
+package generated
+
+import "testing"
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"// Code generated by stringer; DO NOT EDIT.\n\npackage a\n", true},
+		{"// Copyright 2017\n\n// Code generated by protoc-gen-go. DO NOT EDIT.\r\n// source: a.proto\n\npackage a\n", true},
+		{"// Package a does things\npackage a\n", false},
+		{"package a\n\n// Code generated by hand. DO NOT EDIT.\n", false},
+		{"// Code generated by stringer; DO NOT EDIT\npackage a\n", false},
+		{"// Code generated DO NOT EDIT.\npackage a\n", false},
+	}
+
+	for _, test := range tests {
+		if got := Source([]byte(test.src)); got != test.want {
+			t.Errorf("Source(%q) = %v, want %v", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package lintflags
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// golangciNames maps gometalinter linter names to their golangci-lint
+// equivalents where they differ
+var golangciNames = map[string]string{
+	"aligncheck": "maligned",
+	"gas":        "gosec",
+	"gotype":     "typecheck",
+	"vet":        "govet",
+	"vetshadow":  "govet",
+}
+
+// GolangciLinterName returns the name golangci-lint uses for a gometalinter
+// linter
+func GolangciLinterName(name string) string {
+	if n, ok := golangciNames[name]; ok {
+		return n
+	}
+	return name
+}
+
+// GolangciLintArgs translates the flags into arguments for golangci-lint run.
+// Include, exclude, severity and message override flags are not passed since
+// zb applies those to the normalized output itself. Linter specific settings,
+// e.g. --line-length, have no golangci-lint flag and must be set in its own
+// config file.
+func (f *Data) GolangciLintArgs() []string {
+	args := []string{
+		"run",
+		"--out-format", "line-number",
+		"--print-issued-lines=false",
+		"--issues-exit-code", "1",
+		"--max-issues-per-linter", "0",
+		"--max-same-issues", "0",
+		"--exclude-use-default=false",
+	}
+
+	if f.Concurrency != 0 {
+		args = append(args, "-j", fmt.Sprintf("%d", f.Concurrency))
+	}
+
+	if f.NoTests {
+		args = append(args, "--tests=false")
+	}
+
+	if f.Deadline != 0 {
+		args = append(args, "--timeout", f.Deadline.String())
+	}
+
+	for _, v := range f.Skip {
+		args = append(args, "--skip-dirs", v)
+	}
+
+	if f.Fast {
+		args = append(args, "--fast")
+	}
+
+	if f.DisableAll {
+		args = append(args, "--disable-all")
+	}
+
+	if f.EnableAll {
+		args = append(args, "--enable-all")
+	}
+
+	// staticcheck checks are not golangci-lint linters. several gometalinter
+	// linters can map to the same golangci-lint one, enabling it wins.
+	states := map[string]string{}
+	for l, state := range f.linterStates() {
+		if staticcheckCheckRE.MatchString(l) {
+			continue
+		}
+
+		name := GolangciLinterName(l)
+		if states[name] != enable {
+			states[name] = state
+		}
+	}
+
+	var disabled, enabled []string
+	for l, state := range states {
+		// golangci-lint rejects --disable with --disable-all and --enable
+		// with --enable-all
+		if state == disable {
+			if !f.DisableAll {
+				disabled = append(disabled, l)
+			}
+		} else if !f.EnableAll {
+			enabled = append(enabled, l)
+		}
+	}
+
+	sort.Strings(disabled)
+	sort.Strings(enabled)
+
+	if len(disabled) > 0 {
+		args = append(args, disable, strings.Join(disabled, ","))
+	}
+
+	if len(enabled) > 0 {
+		args = append(args, enable, strings.Join(enabled, ","))
+	}
+
+	return args
+}
+
+// staticcheckCheckRE matches staticcheck check names and patterns, e.g. SA1019
+// or ST*
+var staticcheckCheckRE = regexp.MustCompile(`\A(?:SA|S|ST|QF|U)(?:\d+|\d*\*)\z`)
+
+// StaticcheckArgs translates the flags into arguments for staticcheck.
+// Staticcheck checks, e.g. SA1019, that are passed to --enable or --disable
+// are added to its -checks flag.
+func (f *Data) StaticcheckArgs() []string {
+	args := []string{"-f", "text"}
+
+	if f.NoTests {
+		args = append(args, "-tests=false")
+	}
+
+	var checks []string
+
+	for _, v := range f.Enable {
+		if staticcheckCheckRE.MatchString(v) {
+			checks = append(checks, v)
+		}
+	}
+
+	for _, v := range f.Disable {
+		if staticcheckCheckRE.MatchString(v) {
+			checks = append(checks, "-"+v)
+		}
+	}
+
+	if len(checks) > 0 {
+		args = append(args, "-checks", strings.Join(append([]string{"inherit"}, checks...), ","))
+	}
+
+	return args
+}
@@ -21,7 +21,9 @@ var (
 	defaultDuplThreshold  = 50
 	defaultDeadline       = 5 * time.Second
 	defaultSort           = SortNone
-	// defaultFormat         = "{{.Path}}:{{.Line}}:{{if .Col}}{{.Col}}{{end}}:{{.Severity}}: {{.Message}} ({{.Linter}})"
+
+	// DefaultFormat is the template used to output each lint issue
+	DefaultFormat = "{{.Path}}:{{.Line}}:{{if .Col}}{{.Col}}{{end}}:{{.Severity}}: {{.Message}} ({{.Linter}}){{if .Cached}} (cached){{end}}"
 )
 
 type Data struct {
@@ -57,7 +59,8 @@ type Data struct {
 	Severity         cli.StringSlice
 	DisableAll       bool
 	EnableAll        bool
-	// Format           string
+	Backend          string
+	Format           string
 }
 
 func (f *Data) LintFlags() []cli.Flag {
@@ -230,12 +233,18 @@ func (f *Data) LintFlags() []cli.Flag {
 			Usage:       "Enable all linters.",
 			Destination: &f.EnableAll,
 		},
-		// cli.StringFlag{
-		// 	Name:        "format",
-		// 	Usage:       "Output format.",
-		// 	Value:       defaultFormat,
-		// 	Destination: &f.Format,
-		// },
+		cli.StringFlag{
+			Name:        "backend",
+			Usage:       fmt.Sprintf("Lint backend to use (%s). auto uses the backend from the project config, or gometalinter if it is installed, or the builtin backend.", strings.Join(backends, ", ")),
+			Value:       BackendAuto,
+			Destination: &f.Backend,
+		},
+		cli.StringFlag{
+			Name:        "format",
+			Usage:       "Go template used to output each issue. Fields are Path, Line, Col, Severity, Message, Linter and Cached.",
+			Value:       DefaultFormat,
+			Destination: &f.Format,
+		},
 	}
 }
 
@@ -278,7 +287,46 @@ const (
 	enable  = "-E"
 )
 
-func (f *Data) linters() []string {
+// Lint backends
+const (
+	BackendAuto         = "auto"
+	BackendBuiltin      = "builtin"
+	BackendGometalinter = "gometalinter"
+	BackendGolangciLint = "golangci-lint"
+	BackendStaticcheck  = "staticcheck"
+)
+
+var backends = []string{
+	BackendAuto,
+	BackendBuiltin,
+	BackendGometalinter,
+	BackendGolangciLint,
+	BackendStaticcheck,
+}
+
+// LinterEnabled reports whether the named linter should be run, taking into
+// account zb's defaults as well as the enable and disable flags. def is
+// whether the linter is enabled when nothing else specifies otherwise.
+func (f *Data) LinterEnabled(name string, def bool) bool {
+	for _, v := range f.Linter {
+		if v == name || strings.HasPrefix(v, name+":") {
+			return true
+		}
+	}
+
+	state, ok := f.linterStates()[name]
+	if ok {
+		return state == enable
+	}
+
+	if f.DisableAll {
+		return false
+	}
+
+	return def || f.EnableAll
+}
+
+func (f *Data) linterStates() map[string]string {
 	lm := map[string]string{}
 
 	for _, linter := range disabledLinters {
@@ -297,6 +345,12 @@ func (f *Data) linters() []string {
 		lm[v] = enable
 	}
 
+	return lm
+}
+
+func (f *Data) linters() []string {
+	lm := f.linterStates()
+
 	var disabled, enabled []string
 
 	for l, f := range lm {
@@ -416,13 +470,9 @@ func (f *Data) LintArgs() []string {
 		args = append(args, "--errors")
 	}
 
-	if f.JSON {
-		args = append(args, "--json")
-	}
-
-	if f.Checkstyle {
-		args = append(args, "--checkstyle")
-	}
+	// --json, --checkstyle and --format only affect how results are output
+	// and are handled by zb rather than gometalinter so that the results can
+	// be parsed and cached
 
 	if f.EnableGC {
 		args = append(args, "--enable-gc")
@@ -444,9 +494,5 @@ func (f *Data) LintArgs() []string {
 		args = append(args, "--severity", v)
 	}
 
-	// if f.Format != "" && f.Format != defaultFormat {
-	// 	args = append(args, "--format", f.Format)
-	// }
-
 	return append(args, f.linters()...)
 }
@@ -0,0 +1,127 @@
+package project
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"srcd.works/go-git.v4/plumbing"
+
+	"jrubin.io/zb/lib/dependency"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// GenerateDirs returns the directories of the project that aren't packages
+// but whose go files may still contain zb:generate directives. The go files to
+// search are the IgnoredGoFiles of the returned packages. A directory
+// qualifies if all of its go files are excluded from builds (e.g. a directory
+// with only a doc.go that has a "+build ignore" constraint) or if it has go
+// files and is, or is below, a testdata directory, whose go files the go tool
+// never builds. Directories with buildable go files, including those with
+// only a doc.go without a constraint, are packages and their directives are
+// found with the packages. Like the go tool, directories beginning with . or _
+// and vendor directories are skipped.
+func (p *Project) GenerateDirs(ctx zbcontext.Context) ([]*build.Package, error) {
+	var ret []*build.Package
+
+	err := filepath.Walk(p.Dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !fi.IsDir() {
+			return nil
+		}
+
+		elem := fi.Name()
+		if path != p.Dir && (strings.HasPrefix(elem, ".") ||
+			strings.HasPrefix(elem, "_") ||
+			elem == "vendor") {
+			return filepath.SkipDir
+		}
+
+		if inTestdata(p.Dir, path) {
+			files, err := goFiles(path)
+			if err != nil {
+				return err
+			}
+
+			if len(files) > 0 {
+				ret = append(ret, &build.Package{Dir: path, IgnoredGoFiles: files})
+			}
+
+			return nil
+		}
+
+		pkg, err := ctx.ImportDir(path)
+		if _, ok := err.(*build.NoGoError); ok && len(pkg.IgnoredGoFiles) > 0 {
+			ret = append(ret, pkg)
+		}
+
+		return nil
+	})
+
+	return ret, err
+}
+
+// inTestdata reports whether dir is, or is below, a testdata directory of the
+// project in projectDir
+func inTestdata(projectDir, dir string) bool {
+	rel, err := filepath.Rel(projectDir, dir)
+	if err != nil {
+		return false
+	}
+
+	for _, elem := range strings.Split(rel, string(filepath.Separator)) {
+		if elem == "testdata" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// goFiles returns the names of the go files in dir
+func goFiles(dir string) ([]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for _, fi := range fis {
+		if !fi.IsDir() && strings.HasSuffix(fi.Name(), ".go") {
+			ret = append(ret, fi.Name())
+		}
+	}
+
+	return ret, nil
+}
+
+// generateTargets returns the targets of the zb:generate directives in the
+// go files of the GenerateDirs
+func (p *Project) generateTargets(ctx zbcontext.Context, gitCommit *plumbing.Hash) (*dependency.Targets, error) {
+	dirs, err := p.GenerateDirs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var queue []*dependency.Target
+
+	for _, dir := range dirs {
+		gopkg := &dependency.GoPackage{
+			ProjectImportPath: ctx.DirToImportPath(p.Dir),
+			Package:           dir,
+			Hash:              gitCommit,
+		}
+
+		for _, f := range dir.IgnoredGoFiles {
+			file := dependency.NewGoFile(ctx, gopkg, filepath.Join(dir.Dir, f))
+			queue = append(queue, dependency.NewTarget(file, nil))
+		}
+	}
+
+	return collectTargets(ctx, queue...)
+}
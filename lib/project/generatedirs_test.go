@@ -0,0 +1,81 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+func TestGenerateDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	const ignored = "// +build ignore\n\npackage x\n"
+
+	files := map[string]string{
+		"a.go":                              "package a\n",
+		"doconly/doc.go":                    "package doconly\n",
+		"fixtures/doc.go":                   ignored,
+		"fixtures/data.json":                "{}",
+		"mixed/a.go":                        "package mixed\n",
+		"mixed/gen.go":                      ignored,
+		"empty/README":                      "",
+		"pkg/pkg.go":                        "package pkg\n",
+		"pkg/testdata/gen.go":               "package main\n",
+		"pkg/testdata/other.go":             "package other\n",
+		"pkg/testdata/golden.json":          "{}",
+		"pkg/testdata/nested/x_test.go":     "package nested\n",
+		"pkg/testdata/nofiles/data.json":    "{}",
+		"vendor/example.com/v/doc.go":       ignored,
+		"_skip/doc.go":                      ignored,
+		".hidden/doc.go":                    ignored,
+		"pkg/testdata/.hidden/doc.go":       ignored,
+		"pkg/testdata/vendor/example/v.go":  "package v\n",
+		"fixtures/testdata/nested/doc.go":   ignored,
+		"fixtures/testdata/nested/data.txt": "",
+	}
+
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := Project{Dir: dir}
+
+	pkgs, err := p.GenerateDirs(zbcontext.Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string][]string{}
+	for _, pkg := range pkgs {
+		rel, err := filepath.Rel(dir, pkg.Dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[filepath.ToSlash(rel)] = pkg.IgnoredGoFiles
+	}
+
+	want := map[string][]string{
+		"fixtures":                 {"doc.go"},
+		"fixtures/testdata/nested": {"doc.go"},
+		"pkg/testdata":             {"gen.go", "other.go"},
+		"pkg/testdata/nested":      {"x_test.go"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GenerateDirs = %v, want %v", got, want)
+	}
+}
@@ -60,6 +60,10 @@ func (l List) Targets(ctx zbcontext.Context, tt dependency.TargetType) ([]*depen
 		return nil, err
 	}
 
+	if err := unique.LinkGenerators(); err != nil {
+		return nil, err
+	}
+
 	targets := unique.TopologicalSort()
 
 	// set up the waitgroup dependencies
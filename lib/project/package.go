@@ -26,10 +26,10 @@ type Package struct {
 
 	IsVendored bool
 
-	deps                        Packages
-	depsBuilt                   bool
-	includeTestImports          bool
-	pkgHash, testHash, lintHash string
+	deps                                     Packages
+	depsBuilt                                bool
+	includeTestImports                       bool
+	pkgHash, testHash, lintHash, ignoredHash string
 }
 
 func (pkg *Package) BuildPath(projectDir string) string {
@@ -90,7 +90,11 @@ func (pkg *Package) Targets(ctx zbcontext.Context, tt dependency.TargetType, pro
 
 	gopkg := fn(ctx, projectDir, gitCommit)
 
-	queue := []*dependency.Target{dependency.NewTarget(gopkg, nil)}
+	return collectTargets(ctx, dependency.NewTarget(gopkg, nil))
+}
+
+// collectTargets returns the targets and all of their dependencies
+func collectTargets(ctx zbcontext.Context, queue ...*dependency.Target) (*dependency.Targets, error) {
 	unique := dependency.Targets{}
 
 	// recursively add all dependencies
@@ -111,10 +115,19 @@ func (pkg *Package) Targets(ctx zbcontext.Context, tt dependency.TargetType, pro
 		// append these dependencies to the queue
 		for _, dep := range deps {
 			// if ctx.RebuildAll, only return the package itself, and go
-			// generate dependencies, but not other dependencies, this is
-			// because "go install -a" will handle the dependencies itself
-			if _, ok := dep.(*dependency.GoGenerateFile); !ok && ctx.RebuildAll() {
-				continue
+			// generate dependencies (and the go files they are found
+			// through), but not other dependencies, this is because "go
+			// install -a" will handle the dependencies itself
+			switch d := dep.(type) {
+			case *dependency.GoFile, *dependency.GoGenerateFile:
+			case *dependency.GoPackage:
+				if !d.Tool && ctx.RebuildAll() {
+					continue
+				}
+			default:
+				if ctx.RebuildAll() {
+					continue
+				}
 			}
 
 			queue = append(queue, dependency.NewTarget(dep, target))
@@ -180,6 +193,12 @@ func (pkg *Package) Deps(ctx zbcontext.Context) ([]*Package, error) {
 
 const cycle = "cycle"
 
+// ClearLintHash discards the memoized lint hash so that it will be recomputed,
+// e.g. after the package source has been modified
+func (pkg *Package) ClearLintHash() {
+	pkg.lintHash = ""
+}
+
 func (pkg *Package) LintHash(flag *lintflags.Data) (string, error) {
 	if pkg.lintHash != "" {
 		return pkg.lintHash, nil
@@ -190,6 +209,8 @@ func (pkg *Package) LintHash(flag *lintflags.Data) (string, error) {
 	h := sha1.New()
 	fmt.Fprintf(h, "lint\n")
 
+	fmt.Fprintf(h, "%s\n", flag.Backend)
+
 	for _, arg := range flag.LintArgs() {
 		fmt.Fprintf(h, "%s\n", arg)
 	}
@@ -326,6 +347,24 @@ func (pkg *Package) Hash(ctx zbcontext.Context) (string, error) {
 	return pkg.pkgHash, nil
 }
 
+// IgnoredHash returns a hash of the go files in the package directory that were
+// excluded by build constraints
+func (pkg *Package) IgnoredHash() (string, error) {
+	if pkg.ignoredHash != "" {
+		return pkg.ignoredHash, nil
+	}
+
+	h := sha1.New()
+	fmt.Fprintf(h, "ignored\n")
+
+	if err := hashFiles(h, pkg.Package.Dir, pkg.IgnoredGoFiles); err != nil {
+		return "", err
+	}
+
+	pkg.ignoredHash = fmt.Sprintf("%x", h.Sum(nil))
+	return pkg.ignoredHash, nil
+}
+
 func hashFiles(h io.Writer, dir string, files []string) error {
 	for _, file := range files {
 		f, err := os.Open(filepath.Join(dir, file))
@@ -86,6 +86,10 @@ func (p Packages) Targets(ctx zbcontext.Context, tt dependency.TargetType) ([]*d
 		return nil, err
 	}
 
+	if err = unique.LinkGenerators(); err != nil {
+		return nil, err
+	}
+
 	targets := unique.TopologicalSort()
 
 	// set up the waitgroup dependencies
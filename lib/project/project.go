@@ -68,7 +68,24 @@ func (p *Project) fillPackages(ctx zbcontext.Context) error {
 }
 
 func (p *Project) Targets(ctx zbcontext.Context, tt dependency.TargetType) (*dependency.Targets, error) {
-	return p.Packages.targets(ctx, tt, p.Dir, p.GitCommit(ctx.Logger))
+	gitCommit := p.GitCommit(ctx.Logger)
+
+	targets, err := p.Packages.targets(ctx, tt, p.Dir, gitCommit)
+
+	// like test files, the directories that aren't packages are only searched
+	// for zb:generate directives by zb generate and zb test --generate
+	if err != nil || ctx.NoGenerate || !ctx.GenerateTests {
+		return targets, err
+	}
+
+	gen, err := p.generateTargets(ctx, gitCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	targets.Append(gen)
+
+	return targets, nil
 }
 
 func (p *Project) GitCommit(logger slog.Interface) *plumbing.Hash {
@@ -0,0 +1,141 @@
+package zbbench
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// Result is a single line of benchmark output
+type Result struct {
+	Name       string             `json:"name"`
+	Iterations int                `json:"iterations"`
+	Values     map[string]float64 `json:"values"`
+}
+
+// Results maps package import paths to the benchmark results of that package
+type Results map[string][]Result
+
+var benchRE = regexp.MustCompile(`\A(Benchmark[^ \t]+)[ \t]+([0-9]+)[ \t]+(.*)\z`)
+
+// ParseResults extracts the benchmark results from go test output
+func ParseResults(data []byte) []Result {
+	var ret []Result
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		m := benchRE.FindStringSubmatch(strings.TrimSpace(s.Text()))
+		if m == nil {
+			continue
+		}
+
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+
+		r := Result{
+			Name:       m[1],
+			Iterations: n,
+			Values:     map[string]float64{},
+		}
+
+		// the remainder is made up of value unit pairs
+		fields := strings.Fields(m[3])
+		for i := 0; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				break
+			}
+			r.Values[fields[i+1]] = v
+		}
+
+		if len(r.Values) > 0 {
+			ret = append(ret, r)
+		}
+	}
+
+	return ret
+}
+
+func baselineFile(ctx zbcontext.Context, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", errors.Errorf("invalid baseline name: %q", name)
+	}
+
+	return filepath.Join(ctx.CacheDir, "baselines", name+".json"), nil
+}
+
+func readResults(file string) (Results, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var r Results
+	if err = json.Unmarshal(data, &r); err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", file)
+	}
+
+	return r, nil
+}
+
+// LoadBaseline reads the named baseline from the cache directory
+func LoadBaseline(ctx zbcontext.Context, name string) (Results, error) {
+	file, err := baselineFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := readResults(file)
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf("baseline not found: %s", name)
+	}
+
+	return r, err
+}
+
+// SaveBaseline stores the results under the given name. Packages that already
+// exist in the baseline, but that are not in the results, are retained.
+func SaveBaseline(ctx zbcontext.Context, name string, results Results) error {
+	file, err := baselineFile(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	existing, err := readResults(file)
+	if os.IsNotExist(err) {
+		existing = Results{}
+	} else if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		existing = Results{}
+	}
+
+	for importPath, r := range results {
+		existing[importPath] = r
+	}
+
+	data, err := json.MarshalIndent(existing, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, data, 0600)
+}
@@ -0,0 +1,248 @@
+package zbbench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+)
+
+// alpha is the significance level at which a difference is considered real
+const alpha = 0.05
+
+// Comparison of a single metric of a single benchmark against a baseline
+type Comparison struct {
+	ImportPath string
+	Name       string
+	Unit       string
+	Old, New   []float64
+	Delta      float64 // percent change of the mean, positive is an increase
+	P          float64 // p-value, -1 if there were too few samples to compute
+}
+
+// Significant reports whether the difference could be shown to be
+// statistically significant. When there are too few samples to test for
+// significance, all differences are treated as significant.
+func (c Comparison) Significant() bool {
+	return c.P < 0 || c.P < alpha
+}
+
+// Regression reports whether the comparison is significant and worse than the
+// baseline by more than threshold percent
+func (c Comparison) Regression(threshold float64) bool {
+	if !c.Significant() {
+		return false
+	}
+
+	if higherIsBetter(c.Unit) {
+		return -c.Delta > threshold
+	}
+
+	return c.Delta > threshold
+}
+
+func higherIsBetter(unit string) bool {
+	return unit == "MB/s"
+}
+
+// Compare the results against the baseline. Only benchmarks that exist in both
+// are compared.
+func Compare(baseline, results Results) []Comparison {
+	var ret []Comparison
+
+	var importPaths []string
+	for importPath := range results {
+		importPaths = append(importPaths, importPath)
+	}
+	sort.Strings(importPaths)
+
+	for _, importPath := range importPaths {
+		old := group(baseline[importPath])
+		cur := group(results[importPath])
+
+		var names []string
+		for name := range cur {
+			if _, ok := old[name]; ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			var units []string
+			for unit := range cur[name] {
+				if _, ok := old[name][unit]; ok {
+					units = append(units, unit)
+				}
+			}
+			sort.Strings(units)
+
+			for _, unit := range units {
+				c := Comparison{
+					ImportPath: importPath,
+					Name:       name,
+					Unit:       unit,
+					Old:        old[name][unit],
+					New:        cur[name][unit],
+					P:          -1,
+				}
+
+				if om := mean(c.Old); om != 0 {
+					c.Delta = 100 * (mean(c.New) - om) / om
+				}
+
+				if len(c.Old) > 1 && len(c.New) > 1 {
+					c.P = mannWhitneyU(c.Old, c.New)
+				}
+
+				ret = append(ret, c)
+			}
+		}
+	}
+
+	return ret
+}
+
+// group benchmark results by name and then unit
+func group(results []Result) map[string]map[string][]float64 {
+	ret := map[string]map[string][]float64{}
+
+	for _, r := range results {
+		if ret[r.Name] == nil {
+			ret[r.Name] = map[string][]float64{}
+		}
+
+		for unit, v := range r.Values {
+			ret[r.Name][unit] = append(ret[r.Name][unit], v)
+		}
+	}
+
+	return ret
+}
+
+func mean(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, f := range v {
+		sum += f
+	}
+
+	return sum / float64(len(v))
+}
+
+// variation returns the maximum deviation from the mean as a percent
+func variation(v []float64) float64 {
+	m := mean(v)
+	if m == 0 {
+		return 0
+	}
+
+	var d float64
+	for _, f := range v {
+		d = math.Max(d, math.Abs(f-m))
+	}
+
+	return 100 * d / m
+}
+
+// mannWhitneyU returns the two sided p-value of the Mann-Whitney U test of
+// whether x and y were drawn from the same distribution. It uses the normal
+// approximation with correction for ties.
+func mannWhitneyU(x, y []float64) float64 {
+	type sample struct {
+		v float64
+		x bool
+	}
+
+	all := make([]sample, 0, len(x)+len(y))
+	for _, v := range x {
+		all = append(all, sample{v: v, x: true})
+	}
+	for _, v := range y {
+		all = append(all, sample{v: v})
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	n1, n2 := float64(len(x)), float64(len(y))
+	n := n1 + n2
+
+	var r1, tieCorrection float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+
+		// ranks are 1 based, tied values share the average rank
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].x {
+				r1 += rank
+			}
+		}
+
+		t := float64(j - i)
+		tieCorrection += t*t*t - t
+
+		i = j
+	}
+
+	u := r1 - n1*(n1+1)/2
+	mu := n1 * n2 / 2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - tieCorrection/(n*(n-1))))
+
+	if sigma == 0 {
+		return 1
+	}
+
+	// continuity correction
+	z := (math.Abs(u-mu) - 0.5) / sigma
+	if z < 0 {
+		z = 0
+	}
+
+	return math.Erfc(z / math.Sqrt2)
+}
+
+func format(v []float64, unit string) string {
+	return fmt.Sprintf("%.4g %s ± %.0f%%", mean(v), unit, variation(v))
+}
+
+// WriteComparisons writes a table of the comparisons to the Writer, marking
+// those that are regressions beyond threshold percent
+func WriteComparisons(w io.Writer, baseline string, comparisons []Comparison, threshold float64) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "NAME\tOLD (%s)\tNEW\tDELTA\t\n", baseline)
+
+	for _, c := range comparisons {
+		delta := "~"
+		if c.Significant() {
+			delta = fmt.Sprintf("%+.2f%%", c.Delta)
+		}
+
+		if c.P >= 0 {
+			delta += fmt.Sprintf(" (p=%.3f n=%d+%d)", c.P, len(c.Old), len(c.New))
+		}
+
+		var mark string
+		if c.Regression(threshold) {
+			mark = "REGRESSION"
+		}
+
+		fmt.Fprintf(tw, "%s.%s\t%s\t%s\t%s\t%s\n",
+			c.ImportPath,
+			c.Name,
+			format(c.Old, c.Unit),
+			format(c.New, c.Unit),
+			delta,
+			mark,
+		)
+	}
+
+	return tw.Flush()
+}
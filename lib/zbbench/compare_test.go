@@ -0,0 +1,73 @@
+package zbbench
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseResults(t *testing.T) {
+	data := []byte(`goos: linux
+BenchmarkA-8   	1000000000	         0.2643 ns/op	       0 B/op	       0 allocs/op
+BenchmarkB/sub-8   	   20000	     61234 ns/op	  12.50 MB/s
+PASS
+ok  	example.com/a	2.154s (cached)
+`)
+
+	results := ParseResults(data)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+
+	if results[0].Name != "BenchmarkA-8" || results[0].Iterations != 1000000000 {
+		t.Errorf("unexpected result: %+v", results[0])
+	}
+
+	if v := results[0].Values["ns/op"]; v != 0.2643 {
+		t.Errorf("ns/op = %v, want 0.2643", v)
+	}
+
+	if v := results[1].Values["MB/s"]; v != 12.5 {
+		t.Errorf("MB/s = %v, want 12.5", v)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	same := []float64{1, 2, 3, 4, 5}
+	if p := mannWhitneyU(same, same); p < 0.99 {
+		t.Errorf("identical samples p = %v, want ~1", p)
+	}
+
+	x := []float64{10, 11, 12, 13, 14, 15}
+	y := []float64{20, 21, 22, 23, 24, 25}
+	if p := mannWhitneyU(x, y); p >= alpha {
+		t.Errorf("disjoint samples p = %v, want < %v", p, alpha)
+	}
+
+	if p := mannWhitneyU([]float64{1, 1}, []float64{1, 1}); p != 1 {
+		t.Errorf("all ties p = %v, want 1", p)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := Results{"a": {
+		{Name: "BenchmarkA", Values: map[string]float64{"ns/op": 100}},
+	}}
+
+	results := Results{"a": {
+		{Name: "BenchmarkA", Values: map[string]float64{"ns/op": 120}},
+		{Name: "BenchmarkNew", Values: map[string]float64{"ns/op": 1}},
+	}}
+
+	cs := Compare(baseline, results)
+	if len(cs) != 1 {
+		t.Fatalf("got %d comparisons, want 1", len(cs))
+	}
+
+	if math.Abs(cs[0].Delta-20) > 1e-9 {
+		t.Errorf("delta = %v, want 20", cs[0].Delta)
+	}
+
+	if !cs[0].Regression(10) || cs[0].Regression(25) {
+		t.Errorf("unexpected regression result for delta %v", cs[0].Delta)
+	}
+}
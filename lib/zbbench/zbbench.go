@@ -0,0 +1,133 @@
+package zbbench
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"jrubin.io/zb/lib/buildflags"
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbcontext"
+	"jrubin.io/zb/lib/zbtest"
+)
+
+// ZBBench provides methods for working with cached benchmark result files
+type ZBBench struct {
+	buildflags.TestFlagsData
+	Force     bool
+	Save      string
+	Compare   string
+	Threshold float64
+}
+
+// BenchSetup must be called before other methods to complete the
+// configuration from the context
+func (b *ZBBench) BenchSetup(ctx zbcontext.Context) zbcontext.Context {
+	if filepath.Base(ctx.CacheDir) != "bench" {
+		ctx.CacheDir = filepath.Join(ctx.CacheDir, "bench")
+	}
+
+	if b.Bench == "" {
+		b.Bench = "."
+	}
+
+	// only run benchmarks, not tests, unless explicitly requested
+	if b.Run == "" {
+		b.Run = "^$"
+	}
+
+	ctx.BuildArger = &b.TestFlagsData
+	ctx.BuildContext = b.TestFlagsData.BuildContext()
+	return ctx
+}
+
+// BenchArgs returns the arguments to pass to go test to run the benchmarks
+func (b *ZBBench) BenchArgs() []string {
+	return b.TestArgs(nil, nil)
+}
+
+// CacheFile returns the location of the benchmark cache file for a given
+// package
+func (b *ZBBench) CacheFile(ctx zbcontext.Context, p *project.Package) (string, error) {
+	testHash, err := p.TestHash(ctx, &b.TestFlagsData)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha1.New()
+	fmt.Fprintf(h, "bench\n")
+	fmt.Fprintf(h, "test %s\n", testHash)
+
+	for _, arg := range b.BenchArgs() {
+		fmt.Fprintf(h, "%s\n", arg)
+	}
+
+	benchHash := fmt.Sprintf("%x", h.Sum(nil))
+
+	return filepath.Join(
+		ctx.CacheDir,
+		benchHash[:3],
+		fmt.Sprintf("%s.bench", benchHash[3:]),
+	), nil
+}
+
+// HaveResult checks to see if a benchmark result is available for a given
+// package
+func (b *ZBBench) HaveResult(ctx zbcontext.Context, p *project.Package) (bool, error) {
+	if b.Force {
+		return false, nil
+	}
+
+	file, err := b.CacheFile(ctx, p)
+	if err != nil {
+		return false, err
+	}
+
+	fi, err := os.Stat(file)
+	return err == nil && fi.Mode().IsRegular(), nil
+}
+
+// ReadResult from the StringReader and write it to the CacheFile for the
+// given package. The output is returned so that its benchmarks can be parsed.
+func (b *ZBBench) ReadResult(ctx zbcontext.Context, r zbtest.StringReader, p *project.Package) ([]byte, error) {
+	file, err := b.CacheFile(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	data, status, err := zbtest.ReadPackage(r, nil)
+	if err != nil || status == nil {
+		return data, err
+	}
+
+	// don't cache failed runs, their benchmarks are incomplete
+	if status.Result == "FAIL" {
+		return data, nil
+	}
+
+	if err = os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return nil, err
+	}
+
+	return data, ioutil.WriteFile(file, data, 0600)
+}
+
+// ShowResult reads the CacheFile for the given package and writes it to the
+// Writer. The output is also returned so that its benchmarks can be parsed.
+func (b *ZBBench) ShowResult(ctx zbcontext.Context, w io.Writer, p *project.Package) ([]byte, error) {
+	file, err := b.CacheFile(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(data)
+	return data, err
+}
@@ -0,0 +1,142 @@
+package zbconfig
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/src-d/gcfg"
+)
+
+// FileName is the name of the configuration file that is read from the root
+// of a project
+const FileName = ".zbconfig"
+
+// Config is the per project configuration of zb. It is read from a git-config
+// style file in the root of the project.
+//
+//	[matrix "race"]
+//		flag = -race
+//	[matrix "integration"]
+//		tags = integration
+//	[matrix "noCgo"]
+//		env = CGO_ENABLED=0
+//	[lint]
+//		backend = golangci-lint
+type Config struct {
+	Matrix map[string]*Matrix
+	Lint   Lint
+}
+
+// Lint is the configuration of zb lint
+type Lint struct {
+	Backend string
+}
+
+// Matrix is a named configuration that zb test --matrix runs the tests with
+type Matrix struct {
+	Flag []string
+	Tags []string
+	Env  []string
+}
+
+// NamedMatrix is a Matrix along with its name
+type NamedMatrix struct {
+	Name string
+	*Matrix
+}
+
+// Load reads the configuration from the project dir. A missing file is not an
+// error and results in an empty configuration.
+func Load(dir string) (*Config, error) {
+	c := &Config{}
+
+	if dir == "" {
+		return c, nil
+	}
+
+	file := filepath.Join(dir, FileName)
+
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return c, nil
+	}
+
+	if err := gcfg.ReadFileInto(c, file); err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", file)
+	}
+
+	return c, nil
+}
+
+var (
+	loaded   = map[string]*Config{}
+	loadedMu sync.Mutex
+)
+
+// Get returns the configuration of the project dir, reading it only the first
+// time it is requested. Errors are not remembered.
+func Get(dir string) (*Config, error) {
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+
+	if c, ok := loaded[dir]; ok {
+		return c, nil
+	}
+
+	c, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded[dir] = c
+	return c, nil
+}
+
+// MatrixConfigs returns the matrix configurations sorted by name
+func (c *Config) MatrixConfigs() []NamedMatrix {
+	if c == nil {
+		return nil
+	}
+
+	var ret []NamedMatrix
+	for name, m := range c.Matrix {
+		ret = append(ret, NamedMatrix{Name: name, Matrix: m})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+
+	return ret
+}
+
+// GetMatrixConfigs returns the matrix configurations of the project dir,
+// sorted by name. An error naming the missing section is returned if the
+// project doesn't define any.
+func GetMatrixConfigs(dir string) ([]NamedMatrix, error) {
+	if dir == "" {
+		return nil, errors.Errorf(`no [matrix "name"] sections: not in a project with a %s file`, FileName)
+	}
+
+	c, err := Get(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := c.MatrixConfigs()
+	if len(configs) == 0 {
+		return nil, errors.Errorf(`no [matrix "name"] sections in %s`, filepath.Join(dir, FileName))
+	}
+
+	return configs, nil
+}
+
+// LintBackend returns the lint backend configured for the project, if any
+func (c *Config) LintBackend() string {
+	if c == nil {
+		return ""
+	}
+	return c.Lint.Backend
+}
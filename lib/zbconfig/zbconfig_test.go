@@ -0,0 +1,123 @@
+package zbconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "zbconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data != "" {
+		if err = ioutil.WriteFile(filepath.Join(dir, FileName), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	missing := writeConfig(t, "")
+	defer func() { _ = os.RemoveAll(missing) }()
+
+	c, err := Load(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.MatrixConfigs()) != 0 || c.LintBackend() != "" {
+		t.Errorf("expected an empty configuration, got %+v", c)
+	}
+
+	valid := writeConfig(t, "[matrix \"race\"]\n\tflag = -race\n[matrix \"noCgo\"]\n\tenv = CGO_ENABLED=0\n[lint]\n\tbackend = staticcheck\n")
+	defer func() { _ = os.RemoveAll(valid) }()
+
+	if c, err = Load(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	configs := c.MatrixConfigs()
+	if len(configs) != 2 || configs[0].Name != "noCgo" || configs[1].Name != "race" || configs[1].Flag[0] != "-race" {
+		t.Errorf("unexpected matrix configurations: %+v", configs)
+	}
+
+	if c.LintBackend() != "staticcheck" {
+		t.Errorf("lint backend = %q, want staticcheck", c.LintBackend())
+	}
+}
+
+func TestGetMalformed(t *testing.T) {
+	dir := writeConfig(t, "[matrix \"race\"\n\tflag = -race\n")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	if _, err := Get(dir); err == nil {
+		t.Fatal("expected an error for a malformed configuration")
+	}
+
+	// the error isn't remembered, so fixing the file fixes the project
+	if err := ioutil.WriteFile(filepath.Join(dir, FileName), []byte("[lint]\n\tbackend = builtin\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Get(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.LintBackend() != "builtin" {
+		t.Errorf("lint backend = %q, want builtin", c.LintBackend())
+	}
+
+	// other projects are unaffected by the malformed one
+	other := writeConfig(t, "")
+	defer func() { _ = os.RemoveAll(other) }()
+
+	if _, err = Get(other); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetMatrixConfigs(t *testing.T) {
+	missing := writeConfig(t, "")
+	defer func() { _ = os.RemoveAll(missing) }()
+
+	noMatrix := writeConfig(t, "[lint]\n\tbackend = builtin\n")
+	defer func() { _ = os.RemoveAll(noMatrix) }()
+
+	for _, dir := range []string{missing, noMatrix} {
+		_, err := GetMatrixConfigs(dir)
+		if err == nil {
+			t.Errorf("expected an error for %s without a matrix section", dir)
+			continue
+		}
+
+		if want := `no [matrix "name"] sections in ` + filepath.Join(dir, FileName); err.Error() != want {
+			t.Errorf("error = %q, want %q", err, want)
+		}
+	}
+
+	if _, err := GetMatrixConfigs(""); err == nil || !strings.Contains(err.Error(), FileName) {
+		t.Errorf("error outside of a project = %v, want one naming %s", err, FileName)
+	}
+
+	valid := writeConfig(t, "[matrix \"race\"]\n\tflag = -race\n")
+	defer func() { _ = os.RemoveAll(valid) }()
+
+	configs, err := GetMatrixConfigs(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(configs) != 1 || configs[0].Name != "race" {
+		t.Errorf("unexpected matrix configurations: %+v", configs)
+	}
+}
@@ -37,6 +37,7 @@ type Context struct {
 	NoGenerate bool
 
 	ExcludeVendor bool
+	GenerateTests bool // find zb:generate directives in test files too
 }
 
 // Import returns details about the Go package named by the import path,
@@ -58,6 +59,12 @@ func (ctx Context) Import(path, srcDir string) (*build.Package, error) {
 	return ctx.buildContext().Import(path, srcDir, build.ImportComment)
 }
 
+// ImportDir is like Import but processes the Go package found in the named
+// directory.
+func (ctx Context) ImportDir(dir string) (*build.Package, error) {
+	return ctx.buildContext().ImportDir(dir, build.ImportComment)
+}
+
 func (ctx Context) buildContext() *build.Context {
 	if ctx.BuildContext != nil {
 		return ctx.BuildContext
@@ -149,6 +156,31 @@ func QuoteCommand(command string, args []string) string {
 	return command
 }
 
+// GoError is returned by GoExec when the go command fails. Its message is
+// empty, like that of cli.NewExitError, since the output has already been
+// logged.
+type GoError struct {
+	Args   []string
+	Output string
+	Code   int
+}
+
+var _ cli.ExitCoder = (*GoError)(nil)
+
+func (e *GoError) Error() string {
+	return ""
+}
+
+// ExitCode implements cli.ExitCoder
+func (e *GoError) ExitCode() int {
+	return e.Code
+}
+
+// Command returns the go command that failed
+func (e *GoError) Command() string {
+	return QuoteCommand("go", e.Args)
+}
+
 func (ctx *Context) GoExec(args ...string) error {
 	ctx.Logger.Info(QuoteCommand("→ go", args))
 
@@ -165,6 +197,10 @@ func (ctx *Context) GoExec(args ...string) error {
 	if code != ExitOK {
 		level = slog.ErrorLevel
 	}
+
+	// keep the output for the caller, copying it to the log drains buf
+	output := buf.String()
+
 	w := ctx.Logger.Writer(level).Prefix("← ")
 	defer func() { _ = w.Close() }() // nosec
 
@@ -173,7 +209,11 @@ func (ctx *Context) GoExec(args ...string) error {
 	}
 
 	if code != ExitOK {
-		return cli.NewExitError("", code)
+		return &GoError{
+			Args:   args,
+			Output: output,
+			Code:   code,
+		}
 	}
 
 	return nil
@@ -0,0 +1,179 @@
+package zblint
+
+import (
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"jrubin.io/zb/lib/lintflags"
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// Backend runs linters against packages and writes lines of the form
+// file:line:col:severity: message (linter) to the Writer. The returned value
+// is the exit code of the backend.
+type Backend interface {
+	Name() string
+	Run(ctx zbcontext.Context, w io.Writer, pkgs project.Packages) (int, error)
+}
+
+// Backend returns the Backend that was selected by ResolveBackend
+func (l *ZBLint) Backend() (Backend, error) {
+	switch l.Data.Backend {
+	case lintflags.BackendBuiltin:
+		return &builtin{Data: &l.Data}, nil
+	case lintflags.BackendGometalinter:
+		if _, err := exec.LookPath("gometalinter"); err != nil {
+			return nil, err
+		}
+		return &gometalinter{Data: &l.Data}, nil
+	case lintflags.BackendGolangciLint:
+		if _, err := exec.LookPath("golangci-lint"); err != nil {
+			return nil, err
+		}
+		return newGolangciLint(&l.Data), nil
+	case lintflags.BackendStaticcheck:
+		if _, err := exec.LookPath("staticcheck"); err != nil {
+			return nil, err
+		}
+		return newStaticcheck(&l.Data), nil
+	}
+
+	return nil, errors.Errorf("unknown lint backend: %s", l.Data.Backend)
+}
+
+// resolveBackend replaces the auto backend with the one that will actually be
+// used so that lint results from different backends are cached separately
+func resolveBackend(name string) string {
+	if name != "" && name != lintflags.BackendAuto {
+		return name
+	}
+
+	if _, err := exec.LookPath("gometalinter"); err == nil {
+		return lintflags.BackendGometalinter
+	}
+
+	return lintflags.BackendBuiltin
+}
+
+// relDir returns the directory of the package relative to the current working
+// directory, if possible
+func relDir(pkg *project.Package) string {
+	path := pkg.Package.Dir
+	if rel, err := filepath.Rel(zbcontext.CWD, path); err == nil {
+		path = rel
+	}
+	return path
+}
+
+type gometalinter struct {
+	*lintflags.Data
+}
+
+func (g *gometalinter) Name() string {
+	return lintflags.BackendGometalinter
+}
+
+func (g *gometalinter) Run(ctx zbcontext.Context, w io.Writer, pkgs project.Packages) (int, error) {
+	args := g.LintArgs()
+	for _, pkg := range pkgs {
+		args = append(args, relDir(pkg))
+	}
+
+	ctx.Logger.Debug(zbcontext.QuoteCommand("→ gometalinter", args))
+
+	ecmd := exec.Command("gometalinter", args...) // nosec
+	ecmd.Stdout = w
+	ecmd.Stderr = w
+
+	return zbcontext.ExitCode(ecmd.Run())
+}
+
+// maxBatch is the maximum number of packages passed to a single run of a
+// Backend
+const maxBatch = 32
+
+// Batches splits the packages into groups that are each linted by a single
+// run of the Backend
+func Batches(pkgs project.Packages) []project.Packages {
+	var ret []project.Packages
+
+	for len(pkgs) > maxBatch {
+		ret = append(ret, pkgs[:maxBatch])
+		pkgs = pkgs[maxBatch:]
+	}
+
+	if len(pkgs) > 0 {
+		ret = append(ret, pkgs)
+	}
+
+	return ret
+}
+
+// SplitResult demultiplexes the result of linting a batch of packages into a
+// result for each package based on the directory of the file of each issue.
+// Lines of other output are assigned the same way when they begin with the
+// path of a go file, or to the package when they are a "# importpath" header.
+// Issues and output that can't be attributed to one of the packages are
+// logged rather than cached with any of them.
+func (l *ZBLint) SplitResult(ctx zbcontext.Context, pkgs project.Packages, res *Result) map[*project.Package]*Result {
+	ret := map[*project.Package]*Result{}
+	byDir := map[string]*project.Package{}
+	byImportPath := map[string]*project.Package{}
+
+	for _, pkg := range pkgs {
+		ret[pkg] = &Result{}
+		byDir[absPath(pkg.Package.Dir)] = pkg
+		byImportPath[pkg.ImportPath] = pkg
+	}
+
+	for _, line := range strings.SplitAfter(res.Output, "\n") {
+		if line == "" {
+			continue
+		}
+
+		var pkg *project.Package
+		if path := outputPath(line); path != "" {
+			pkg = byDir[filepath.Dir(absPath(path))]
+		} else if strings.HasPrefix(line, "# ") {
+			pkg = byImportPath[strings.TrimSpace(line[2:])]
+		}
+
+		if pkg == nil {
+			ctx.Logger.Warn(strings.TrimSuffix(line, "\n"))
+			continue
+		}
+
+		ret[pkg].Output += line
+	}
+
+	for _, i := range res.Issues {
+		pkg, ok := byDir[filepath.Dir(absPath(i.Path))]
+		if !ok {
+			ctx.Logger.WithField("issue", i.String()).Debug("issue is not in any of the linted packages, dropping")
+			continue
+		}
+
+		ret[pkg].Issues = append(ret[pkg].Issues, i)
+	}
+
+	for _, r := range ret {
+		sortIssues(r.Issues, l.Sort)
+	}
+
+	return ret
+}
+
+// outputPath returns the path of the go file that a line of linter output
+// begins with, if any
+func outputPath(line string) string {
+	i := strings.Index(line, ":")
+	if i <= 0 || !strings.HasSuffix(line[:i], ".go") {
+		return ""
+	}
+	return line[:i]
+}
@@ -0,0 +1,128 @@
+package zblint
+
+import (
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"jrubin.io/slog"
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+func testPackages(dirs ...string) project.Packages {
+	var pkgs project.Packages
+	for _, dir := range dirs {
+		pkgs = append(pkgs, &project.Package{Package: &build.Package{Dir: dir, ImportPath: dir}})
+	}
+	return pkgs
+}
+
+func TestBatches(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []int
+	}{
+		{0, nil},
+		{1, []int{1}},
+		{maxBatch, []int{maxBatch}},
+		{maxBatch + 1, []int{maxBatch, 1}},
+		{2*maxBatch + 5, []int{maxBatch, maxBatch, 5}},
+	}
+
+	for _, test := range tests {
+		var dirs []string
+		for i := 0; i < test.n; i++ {
+			dirs = append(dirs, fmt.Sprintf("/p%03d", i))
+		}
+
+		var got []int
+		var all project.Packages
+		for _, batch := range Batches(testPackages(dirs...)) {
+			got = append(got, len(batch))
+			all = append(all, batch...)
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Batches of %d = %v, want %v", test.n, got, test.want)
+		}
+
+		for i, pkg := range all {
+			if pkg.Dir != dirs[i] {
+				t.Errorf("Batches of %d reordered the packages: %s at %d", test.n, pkg.Dir, i)
+				break
+			}
+		}
+	}
+}
+
+func TestSplitResult(t *testing.T) {
+	root := filepath.FromSlash("/src/example.com/p")
+	pkgs := testPackages(root, filepath.Join(root, "a"), filepath.Join(root, "b"))
+
+	issue := func(path string, line int) Issue {
+		return Issue{Path: filepath.Join(root, filepath.FromSlash(path)), Line: line, Severity: SeverityWarning, Linter: "golint"}
+	}
+
+	res := &Result{
+		Issues: []Issue{
+			issue("b/b.go", 9),
+			issue("a/a.go", 3),
+			issue("p.go", 1),
+			issue("b/b.go", 2),
+			issue("a/sub/c.go", 4), // not one of the packages
+		},
+		Output: "# " + pkgs[1].ImportPath + "\n" +
+			filepath.Join(root, "a", "a.go") + ":1: a.go is not gofmted\n" +
+			filepath.Join(root, "b", "b_test.go") + ": can't parse\n" +
+			filepath.Join(root, "a", "sub", "c.go") + ":2: not a package\n" +
+			"other output\n",
+	}
+
+	var h testHandler
+	logger := slog.New()
+	logger.RegisterHandler(slog.DebugLevel, &h)
+	ctx := zbcontext.Context{Logger: logger}
+
+	var l ZBLint
+	got := l.SplitResult(ctx, pkgs, res)
+
+	want := map[*project.Package]*Result{
+		pkgs[0]: {Issues: []Issue{issue("p.go", 1)}},
+		pkgs[1]: {
+			Issues: []Issue{issue("a/a.go", 3)},
+			Output: "# " + pkgs[1].ImportPath + "\n" + filepath.Join(root, "a", "a.go") + ":1: a.go is not gofmted\n",
+		},
+		pkgs[2]: {
+			Issues: []Issue{issue("b/b.go", 2), issue("b/b.go", 9)},
+			Output: filepath.Join(root, "b", "b_test.go") + ": can't parse\n",
+		},
+	}
+
+	for pkg, w := range want {
+		if !reflect.DeepEqual(got[pkg], w) {
+			t.Errorf("result of %s = %+v, want %+v", pkg.Dir, got[pkg], w)
+		}
+	}
+
+	// the output and issue that aren't in any of the packages are logged
+	wantMessages := []string{
+		filepath.Join(root, "a", "sub", "c.go") + ":2: not a package",
+		"other output",
+		"issue is not in any of the linted packages, dropping",
+	}
+
+	if !reflect.DeepEqual(h.messages, wantMessages) {
+		t.Errorf("logged %q, want %q", h.messages, wantMessages)
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("got results for %d packages, want %d", len(got), len(want))
+	}
+
+	if empty := l.SplitResult(ctx, nil, res); len(empty) != 0 {
+		t.Errorf("SplitResult of no packages = %v, want none", empty)
+	}
+}
@@ -0,0 +1,308 @@
+package zblint
+
+import (
+	"bytes"
+	"crypto/sha1" // nosec
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"jrubin.io/slog"
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// BaselineFile is the name of the file, in the project directory, that
+// records the issues that already existed when the baseline was written
+const BaselineFile = ".zblint-baseline.json"
+
+const baselineVersion = 1
+
+// BaselineEntry identifies a single issue recorded in the baseline. Line and
+// Message are informational, issues are matched using the other fields so
+// that entries survive unrelated changes to the file.
+type BaselineEntry struct {
+	File        string `json:"file"`
+	Linter      string `json:"linter"`
+	Fingerprint string `json:"fingerprint"`
+	Anchor      string `json:"anchor"`
+	Line        int    `json:"line"`
+	Message     string `json:"message"`
+}
+
+func (e BaselineEntry) key() string {
+	return e.File + "\x00" + e.Linter + "\x00" + e.Fingerprint + "\x00" + e.Anchor
+}
+
+// Baseline is the content of a BaselineFile
+type Baseline struct {
+	Version int             `json:"version"`
+	Issues  []BaselineEntry `json:"issues"`
+}
+
+// projectPath returns the project directory containing path and the path
+// relative to it. projectDir is empty if the path isn't in a project.
+func projectPath(path string) (projectDir, rel string) {
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(zbcontext.CWD, abs)
+	}
+
+	projectDir = zbcontext.GitDir(filepath.Dir(abs))
+	if projectDir == "" {
+		return "", path
+	}
+
+	rel, err := filepath.Rel(projectDir, abs)
+	if err != nil {
+		return "", path
+	}
+
+	return projectDir, filepath.ToSlash(rel)
+}
+
+var (
+	digitsRE = regexp.MustCompile(`\d+`)
+	spaceRE  = regexp.MustCompile(`\s+`)
+)
+
+func shortHash(s string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(s)))[:16] // nosec
+}
+
+// fingerprint of the issue message that ignores numbers, which often change
+// without the issue changing (e.g. cyclomatic complexity or line lengths)
+func fingerprint(message string) string {
+	message = digitsRE.ReplaceAllString(message, "N")
+	message = spaceRE.ReplaceAllString(strings.TrimSpace(message), " ")
+	return shortHash(message)
+}
+
+// anchors computes line anchors, the hash of the content of the line with the
+// issue without any whitespace, so that issues can be matched even when they
+// move or the line is reindented or reformatted
+type anchors map[string][][]byte
+
+func (a anchors) anchor(path string, line int) string {
+	lines, ok := a[path]
+	if !ok {
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			lines = bytes.Split(data, []byte("\n"))
+		}
+		a[path] = lines
+	}
+
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	return shortHash(spaceRE.ReplaceAllString(string(lines[line-1]), ""))
+}
+
+func (a anchors) entry(i Issue) (string, BaselineEntry) {
+	projectDir, rel := projectPath(i.Path)
+
+	return projectDir, BaselineEntry{
+		File:        rel,
+		Linter:      i.Linter,
+		Fingerprint: fingerprint(i.Message),
+		Anchor:      a.anchor(i.Path, i.Line),
+		Line:        i.Line,
+		Message:     i.Message,
+	}
+}
+
+func loadBaseline(projectDir string) (*Baseline, error) {
+	file := filepath.Join(projectDir, BaselineFile)
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return &Baseline{Version: baselineVersion}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var b Baseline
+	if err = json.Unmarshal(data, &b); err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", file)
+	}
+
+	return &b, nil
+}
+
+func saveBaseline(projectDir string, b *Baseline) error {
+	sort.SliceStable(b.Issues, func(i, j int) bool {
+		x, y := b.Issues[i], b.Issues[j]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		if x.Line != y.Line {
+			return x.Line < y.Line
+		}
+		return x.key() < y.key()
+	})
+
+	if b.Issues == nil {
+		b.Issues = []BaselineEntry{}
+	}
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(projectDir, BaselineFile), append(data, '\n'), 0644)
+}
+
+// lintedDirs returns the project relative directories of the packages
+// grouped by project directory
+func lintedDirs(pkgs project.Packages) map[string]map[string]bool {
+	ret := map[string]map[string]bool{}
+
+	for _, pkg := range pkgs {
+		projectDir := zbcontext.GitDir(pkg.Package.Dir)
+		if projectDir == "" {
+			continue
+		}
+
+		rel, err := filepath.Rel(projectDir, pkg.Package.Dir)
+		if err != nil {
+			continue
+		}
+
+		if ret[projectDir] == nil {
+			ret[projectDir] = map[string]bool{}
+		}
+
+		ret[projectDir][filepath.ToSlash(rel)] = true
+	}
+
+	return ret
+}
+
+func entryDir(e BaselineEntry) string {
+	return filepath.ToSlash(filepath.Dir(filepath.FromSlash(e.File)))
+}
+
+// WriteBaselines records the issues in the baseline file of each project.
+// Entries for packages that were not linted are retained.
+func (l *ZBLint) WriteBaselines(ctx zbcontext.Context, issues []Issue, pkgs project.Packages) error {
+	dirs := lintedDirs(pkgs)
+	entries := map[string][]BaselineEntry{}
+	a := anchors{}
+
+	for _, i := range issues {
+		projectDir, e := a.entry(i)
+		if projectDir == "" {
+			continue
+		}
+		entries[projectDir] = append(entries[projectDir], e)
+	}
+
+	for projectDir, linted := range dirs {
+		b, err := loadBaseline(projectDir)
+		if err != nil {
+			return err
+		}
+
+		var keep []BaselineEntry
+		for _, e := range b.Issues {
+			if !linted[entryDir(e)] {
+				keep = append(keep, e)
+			}
+		}
+
+		b.Version = baselineVersion
+		b.Issues = append(keep, entries[projectDir]...)
+
+		if err = saveBaseline(projectDir, b); err != nil {
+			return err
+		}
+
+		ctx.Logger.WithFields(slog.Fields{
+			"file":   filepath.Join(projectDir, BaselineFile),
+			"issues": len(b.Issues),
+		}).Info("wrote lint baseline")
+	}
+
+	return nil
+}
+
+// ApplyBaselines removes the issues that are recorded in the baseline file of
+// their project. Baseline entries for linted packages that no longer match any
+// issue are reported as fixed.
+func (l *ZBLint) ApplyBaselines(ctx zbcontext.Context, issues []Issue, pkgs project.Packages) ([]Issue, error) {
+	dirs := lintedDirs(pkgs)
+	baselines := map[string]map[string][]BaselineEntry{}
+
+	for projectDir := range dirs {
+		b, err := loadBaseline(projectDir)
+		if err != nil {
+			return nil, err
+		}
+
+		m := map[string][]BaselineEntry{}
+		for _, e := range b.Issues {
+			m[e.key()] = append(m[e.key()], e)
+		}
+		baselines[projectDir] = m
+	}
+
+	var ret []Issue
+	a := anchors{}
+
+	for _, i := range issues {
+		projectDir, e := a.entry(i)
+
+		m := baselines[projectDir]
+		if len(m[e.key()]) > 0 {
+			m[e.key()] = m[e.key()][1:]
+			continue
+		}
+
+		ret = append(ret, i)
+	}
+
+	var fixed []BaselineEntry
+	for projectDir, m := range baselines {
+		for _, entries := range m {
+			for _, e := range entries {
+				if dirs[projectDir][entryDir(e)] {
+					e.File = filepath.Join(projectDir, filepath.FromSlash(e.File))
+					fixed = append(fixed, e)
+				}
+			}
+		}
+	}
+
+	sort.Slice(fixed, func(i, j int) bool {
+		if fixed[i].File != fixed[j].File {
+			return fixed[i].File < fixed[j].File
+		}
+		return fixed[i].Line < fixed[j].Line
+	})
+
+	for _, e := range fixed {
+		ctx.Logger.WithFields(slog.Fields{
+			"file":   e.File,
+			"line":   e.Line,
+			"linter": e.Linter,
+		}).Info("baseline issue fixed: " + e.Message)
+	}
+
+	if len(fixed) > 0 {
+		ctx.Logger.WithField("fixed", len(fixed)).Info("run zb lint --write-baseline to remove fixed issues from the baseline")
+	}
+
+	return ret, nil
+}
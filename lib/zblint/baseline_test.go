@@ -0,0 +1,145 @@
+package zblint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"jrubin.io/slog"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// testProject creates a git project directory containing the files
+func testProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "zblint")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Mkdir(filepath.Join(dir, ".git"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range files {
+		writeFile(t, filepath.Join(dir, filepath.FromSlash(name)), data)
+	}
+
+	return dir
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"cyclomatic complexity 12 of function f() is high", "cyclomatic complexity 15 of function f() is high", true},
+		{"line is 130 characters", "line  is 131 characters ", true},
+		{"exported function Foo should have comment", "exported function Bar should have comment", false},
+	}
+
+	for _, test := range tests {
+		if same := fingerprint(test.a) == fingerprint(test.b); same != test.same {
+			t.Errorf("fingerprint(%q) == fingerprint(%q) is %v, want %v", test.a, test.b, same, test.same)
+		}
+	}
+}
+
+func TestBaselines(t *testing.T) {
+	dir := testProject(t, map[string]string{
+		"a/a.go": "package a\n\nfunc f() {\n\tx := 1\n}\n",
+		"b/b.go": "package b\n\nvar y = 2\n",
+	})
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	ctx := zbcontext.Context{Logger: slog.New()}
+	pkgs := testPackages(filepath.Join(dir, "a"), filepath.Join(dir, "b"))
+
+	aFile := filepath.Join(dir, "a", "a.go")
+	bFile := filepath.Join(dir, "b", "b.go")
+
+	old := []Issue{
+		{Path: aFile, Line: 4, Linter: "ineffassign", Message: "ineffectual assignment to x"},
+		{Path: aFile, Line: 3, Linter: "gocyclo", Message: "cyclomatic complexity 12 of function f() is high"},
+		{Path: bFile, Line: 3, Linter: "golint", Message: "exported var y should have comment"},
+	}
+
+	var l ZBLint
+	if err := l.WriteBaselines(ctx, old, pkgs); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := loadBaseline(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b.Issues) != 3 || b.Issues[0].File != "a/a.go" || b.Issues[0].Line != 3 || b.Issues[2].File != "b/b.go" {
+		t.Fatalf("unexpected baseline: %+v", b.Issues)
+	}
+
+	// lines are added above the issues, the line with the ineffassign issue
+	// is reformatted, the complexity changes and the content of the line with
+	// the golint issue changes
+	writeFile(t, aFile, "package a\n\n// f does things\n\nfunc f() {\n    x:=  1 \n}\n")
+	writeFile(t, bFile, "package b\n\nvar y = 3\n")
+
+	current := []Issue{
+		{Path: aFile, Line: 6, Linter: "ineffassign", Message: "ineffectual assignment to x"},
+		{Path: aFile, Line: 5, Linter: "gocyclo", Message: "cyclomatic complexity 13 of function f() is high"},
+		{Path: aFile, Line: 6, Linter: "vet", Message: "x declared but not used"},
+		{Path: bFile, Line: 3, Linter: "golint", Message: "exported var y should have comment"},
+	}
+
+	got, err := l.ApplyBaselines(ctx, current, pkgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Issue{current[2], current[3]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ApplyBaselines = %+v, want %+v", got, want)
+	}
+
+	// an issue recorded once only suppresses one occurrence
+	twice := []Issue{current[0], current[0]}
+	if got, err = l.ApplyBaselines(ctx, twice, pkgs); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 {
+		t.Errorf("ApplyBaselines of a repeated issue = %+v, want one issue", got)
+	}
+
+	// entries of packages that aren't linted are kept when writing
+	if err = l.WriteBaselines(ctx, nil, pkgs[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err = loadBaseline(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b.Issues) != 1 || b.Issues[0].File != "b/b.go" {
+		t.Errorf("unexpected baseline after linting a: %+v", b.Issues)
+	}
+}
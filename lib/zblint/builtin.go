@@ -0,0 +1,308 @@
+package zblint
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pkg/errors"
+
+	"jrubin.io/zb/lib/lintflags"
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// builtin is a lint backend that does not require gometalinter. Formatting
+// checks are run in process while go vet, and any other supported linters that
+// are installed, are executed directly.
+type builtin struct {
+	*lintflags.Data
+}
+
+// Severities of issues
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+func (b *builtin) Name() string {
+	return lintflags.BackendBuiltin
+}
+
+func (b *builtin) Run(ctx zbcontext.Context, w io.Writer, pkgs project.Packages) (int, error) {
+	var dirs, files []string
+	for _, pkg := range pkgs {
+		dirs = append(dirs, relDir(pkg))
+		files = append(files, lintFiles(pkg, b.NoTests)...)
+	}
+
+	linters := b.linters(ctx)
+
+	f, err := newFilter(b.Data)
+	if err != nil {
+		return zbcontext.ExitFailed, err
+	}
+
+	// the deadline applies to each package, a batch of packages gets the
+	// deadlines of all of them
+	dctx := context.Background()
+	if b.Deadline > 0 {
+		var cancel context.CancelFunc
+		dctx, cancel = context.WithTimeout(dctx, b.Deadline*time.Duration(len(pkgs)))
+		defer cancel()
+	}
+
+	concurrency := b.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	results := make([][]Issue, len(linters))
+
+	var group errgroup.Group
+	for i, l := range linters {
+		i, l := i, l
+		group.Go(func() error {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			issues, err := b.run(dctx, ctx, l, dirs, files)
+			if err != nil {
+				return errors.Wrapf(err, "error running %s", l.name)
+			}
+
+			results[i] = issues
+			return nil
+		})
+	}
+
+	if err = group.Wait(); err != nil {
+		return zbcontext.ExitFailed, err
+	}
+
+	var issues []Issue
+	for _, r := range results {
+		for _, i := range r {
+			if f.keep(&i) {
+				issues = append(issues, i)
+			}
+		}
+	}
+
+	sortIssues(issues, b.Sort)
+
+	for _, i := range issues {
+		if _, err = fmt.Fprintf(w, "%s\n", i); err != nil {
+			return zbcontext.ExitFailed, err
+		}
+	}
+
+	if len(issues) > 0 {
+		return zbcontext.ExitFailed, nil
+	}
+
+	return zbcontext.ExitOK, nil
+}
+
+// linters returns the linters that are enabled and available
+func (b *builtin) linters(ctx zbcontext.Context) []linter {
+	var ret []linter
+
+	for _, l := range builtinLinters {
+		if !b.LinterEnabled(l.name, l.enabled) {
+			continue
+		}
+
+		if b.Fast && !l.fast {
+			continue
+		}
+
+		if l.command != "" {
+			if _, err := exec.LookPath(l.command); err != nil {
+				ctx.Logger.WithField("linter", l.name).Debug("linter is not installed, skipping")
+				continue
+			}
+		}
+
+		ret = append(ret, l)
+	}
+
+	return ret
+}
+
+func (b *builtin) run(dctx context.Context, ctx zbcontext.Context, l linter, dirs, files []string) ([]Issue, error) {
+	var issues []Issue
+
+	if l.check != nil {
+		var err error
+		if issues, err = l.check(ctx, files); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if issues, err = b.exec(dctx, ctx, l, dirs, files); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range issues {
+		issues[i].Linter = l.name
+		if issues[i].Severity == "" {
+			issues[i].Severity = l.severity
+		}
+	}
+
+	return issues, nil
+}
+
+// exec runs an external linter and parses its output. Linters generally exit
+// with a non-zero status when they report issues, so failures are only logged
+// when no issues could be parsed. An error is returned if the deadline is
+// exceeded so that the incomplete results aren't cached.
+func (b *builtin) exec(dctx context.Context, ctx zbcontext.Context, l linter, dirs, files []string) ([]Issue, error) {
+	args := l.args(b.Data, dirs, files)
+
+	linted := map[string]bool{}
+	for _, dir := range dirs {
+		linted[dir] = true
+	}
+
+	ctx.Logger.Debug(zbcontext.QuoteCommand("→ "+l.command, args))
+
+	ecmd := exec.CommandContext(dctx, l.command, args...) // nosec
+	out, err := ecmd.CombinedOutput()
+
+	if dctx.Err() == context.DeadlineExceeded {
+		return nil, errors.New("deadline exceeded")
+	}
+
+	var issues []Issue
+	var unmatched []string
+
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		i, ok := l.parse(s.Text())
+		if !ok {
+			unmatched = append(unmatched, s.Text())
+			continue
+		}
+
+		if b.NoTests && strings.HasSuffix(i.Path, "_test.go") && linted[filepath.Dir(i.Path)] {
+			continue
+		}
+
+		issues = append(issues, i)
+	}
+
+	if err != nil && len(issues) == 0 {
+		logger := ctx.Logger.WithField("linter", l.name).WithError(err)
+		if b.Debug {
+			logger.Warn(strings.Join(unmatched, "\n"))
+		} else {
+			logger.Debug("linter failed")
+		}
+	}
+
+	return issues, nil
+}
+
+type filter struct {
+	include, exclude []*regexp.Regexp
+	severity         map[string]string
+	messages         map[string]string
+	errors           bool
+}
+
+func compileAll(exprs []string) ([]*regexp.Regexp, error) {
+	var ret []*regexp.Regexp
+
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, re)
+	}
+
+	return ret, nil
+}
+
+// linterMap parses values of the form linter:value
+func linterMap(values []string) map[string]string {
+	ret := map[string]string{}
+
+	for _, v := range values {
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) == 2 {
+			ret[parts[0]] = parts[1]
+		}
+	}
+
+	return ret
+}
+
+func newFilter(d *lintflags.Data) (*filter, error) {
+	f := filter{
+		severity: linterMap(d.Severity),
+		messages: linterMap(d.MessageOverrides),
+		errors:   d.Errors,
+	}
+
+	var err error
+
+	if f.include, err = compileAll(d.Include); err != nil {
+		return nil, err
+	}
+
+	if f.exclude, err = compileAll(d.Exclude); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// keep applies the severity and message overrides to the issue and reports
+// whether it should be included in the results
+func (f *filter) keep(i *Issue) bool {
+	if s, ok := f.severity[i.Linter]; ok {
+		i.Severity = s
+	}
+
+	if m, ok := f.messages[i.Linter]; ok {
+		i.Message = strings.Replace(m, "{message}", i.Message, -1)
+	}
+
+	if f.errors && i.Severity != SeverityError {
+		return false
+	}
+
+	s := i.String()
+
+	for _, re := range f.exclude {
+		if re.MatchString(s) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+
+	for _, re := range f.include {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
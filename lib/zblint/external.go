@@ -0,0 +1,150 @@
+package zblint
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"jrubin.io/zb/lib/lintflags"
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// external is a lint backend that executes a single tool that runs many
+// linters. Its output is normalized into issues so that they can be filtered
+// and cached the same way as those of the other backends.
+type external struct {
+	*lintflags.Data
+	command string
+	args    []string
+	parser  linter
+
+	// normalize, if set, is called for each parsed issue before it is
+	// filtered
+	normalize func(i *Issue)
+}
+
+func (e *external) Name() string {
+	return e.command
+}
+
+func (e *external) Run(ctx zbcontext.Context, w io.Writer, pkgs project.Packages) (int, error) {
+	f, err := newFilter(e.Data)
+	if err != nil {
+		return zbcontext.ExitFailed, err
+	}
+
+	var dirs []string
+	for _, pkg := range pkgs {
+		dirs = append(dirs, relDir(pkg))
+	}
+
+	args := append(append([]string{}, e.args...), pkgArgs(dirs)...)
+
+	ctx.Logger.Debug(zbcontext.QuoteCommand("→ "+e.command, args))
+
+	ecmd := exec.Command(e.command, args...) // nosec
+	out, runErr := ecmd.CombinedOutput()
+
+	var parsed int
+	var issues []Issue
+
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		i, ok := e.parser.parse(s.Text())
+		if !ok {
+			// passed through so that it is kept as output of the result
+			if _, err = fmt.Fprintln(w, s.Text()); err != nil {
+				return zbcontext.ExitFailed, err
+			}
+			continue
+		}
+
+		parsed++
+
+		if i.Linter == "" {
+			i.Linter = e.command
+		}
+
+		if i.Severity == "" {
+			i.Severity = SeverityWarning
+		}
+
+		if e.normalize != nil {
+			e.normalize(&i)
+		}
+
+		if f.keep(&i) {
+			issues = append(issues, i)
+		}
+	}
+
+	sortIssues(issues, e.Sort)
+
+	for _, i := range issues {
+		if _, err = fmt.Fprintf(w, "%s\n", i); err != nil {
+			return zbcontext.ExitFailed, err
+		}
+	}
+
+	// the tools exit with a non-zero status when they report issues, the
+	// status only matters when nothing could be parsed
+	if parsed == 0 {
+		return zbcontext.ExitCode(runErr)
+	}
+
+	if len(issues) > 0 {
+		return zbcontext.ExitFailed, nil
+	}
+
+	return zbcontext.ExitOK, nil
+}
+
+// golangciLintPattern matches golangci-lint output in the line-number format
+var golangciLintPattern = regexp.MustCompile(`\A(?P<path>[^\s:]+\.go):(?P<line>\d+):(?:(?P<col>\d+):)?\s*(?P<message>.+?)\s+\((?P<linter>\w+)\)\z`)
+
+// golangciLintErrors are the golangci-lint linters whose issues are errors
+var golangciLintErrors = map[string]bool{
+	"govet":     true,
+	"typecheck": true,
+}
+
+func newGolangciLint(d *lintflags.Data) Backend {
+	return &external{
+		Data:    d,
+		command: lintflags.BackendGolangciLint,
+		args:    d.GolangciLintArgs(),
+		parser:  linter{pattern: golangciLintPattern},
+		normalize: func(i *Issue) {
+			if golangciLintErrors[i.Linter] {
+				i.Severity = SeverityError
+			}
+		},
+	}
+}
+
+// staticcheckPattern matches staticcheck output in the text format
+var staticcheckPattern = regexp.MustCompile(`\A(?P<path>[^\s:]+\.go):(?P<line>\d+):(?:(?P<col>\d+):)?\s*(?P<message>.+?)\s+\((?P<check>[\w-]+)\)\z`)
+
+func newStaticcheck(d *lintflags.Data) Backend {
+	return &external{
+		Data:    d,
+		command: lintflags.BackendStaticcheck,
+		args:    d.StaticcheckArgs(),
+		parser: linter{
+			pattern: staticcheckPattern,
+			message: "{check}: {message}",
+		},
+		normalize: func(i *Issue) {
+			// packages that fail to type check are reported as compile
+			// errors
+			if strings.HasPrefix(i.Message, "compile: ") {
+				i.Severity = SeverityError
+			}
+		},
+	}
+}
@@ -0,0 +1,280 @@
+package zblint
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"jrubin.io/slog"
+	"jrubin.io/zb/lib/generated"
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// removeUnusedImports deletes the lines of imports that are not used by the
+// file. Imports that share a line with another import are left alone.
+func removeUnusedImports(ctx zbcontext.Context, path string, src []byte, names map[string]string) []byte {
+	fset := token.NewFileSet()
+
+	f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return src
+	}
+
+	unused := map[*ast.ImportSpec]bool{}
+	for _, spec := range unusedImports(ctx, f, absPath(filepath.Dir(path)), names) {
+		unused[spec] = true
+	}
+
+	if len(unused) == 0 {
+		return src
+	}
+
+	del := map[int]bool{}
+	deleteLines := func(from, to token.Pos) {
+		for l := fset.Position(from).Line; l <= fset.Position(to).Line; l++ {
+			del[l] = true
+		}
+	}
+
+	lineCount := map[int]int{}
+	for _, spec := range f.Imports {
+		lineCount[fset.Position(spec.Pos()).Line]++
+	}
+
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+
+		var n int
+		for _, spec := range gd.Specs {
+			if unused[spec.(*ast.ImportSpec)] {
+				n++
+			}
+		}
+
+		if n == len(gd.Specs) {
+			deleteLines(gd.Pos(), gd.End())
+			continue
+		}
+
+		for _, s := range gd.Specs {
+			spec := s.(*ast.ImportSpec)
+			if unused[spec] && lineCount[fset.Position(spec.Pos()).Line] == 1 {
+				deleteLines(spec.Pos(), spec.End())
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	for i, line := range bytes.SplitAfter(src, []byte("\n")) {
+		if !del[i+1] {
+			buf.Write(line)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// groupImports inserts blank lines between adjacent standard library and
+// other imports
+func groupImports(path string, src []byte) []byte {
+	fset := token.NewFileSet()
+
+	f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return src
+	}
+
+	breaks := map[int]bool{}
+	for _, spec := range ungroupedImports(fset, f) {
+		breaks[fset.Position(spec.Pos()).Line] = true
+	}
+
+	if len(breaks) == 0 {
+		return src
+	}
+
+	var buf bytes.Buffer
+	for i, line := range bytes.SplitAfter(src, []byte("\n")) {
+		if breaks[i+1] {
+			buf.WriteByte('\n')
+		}
+		buf.Write(line)
+	}
+
+	return buf.Bytes()
+}
+
+func (l *ZBLint) fixable(path string) bool {
+	for is := range l.ignoreSuffixMap {
+		if strings.HasSuffix(path, is) {
+			return false
+		}
+	}
+	return !generated.File(absPath(path))
+}
+
+// fixFile applies the gofmt and goimports fixes to a file and returns the
+// names of the fixes that changed it
+func (l *ZBLint) fixFile(ctx zbcontext.Context, path string, names map[string]string) ([]string, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixes []string
+	res := src
+
+	if l.LinterEnabled("goimports", true) {
+		fixed := groupImports(path, removeUnusedImports(ctx, path, res, names))
+		if !bytes.Equal(fixed, res) {
+			fixes = append(fixes, "goimports")
+			res = fixed
+		}
+	}
+
+	// goimports also formats the source
+	if l.LinterEnabled("gofmt", true) || len(fixes) > 0 {
+		fixed, err := format.Source(res)
+		if err == nil && !bytes.Equal(fixed, res) {
+			fixes = append(fixes, "gofmt")
+			res = fixed
+		}
+	}
+
+	if len(fixes) == 0 {
+		return nil, nil
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return fixes, ioutil.WriteFile(path, res, fi.Mode())
+}
+
+// misspell runs misspell -w on the files and returns the ones it changed
+func misspell(ctx zbcontext.Context, files []string) ([]string, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	if _, err := exec.LookPath("misspell"); err != nil {
+		ctx.Logger.WithField("linter", "misspell").Debug("linter is not installed, skipping fixes")
+		return nil, nil
+	}
+
+	before := map[string][]byte{}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		before[file] = data
+	}
+
+	args := append([]string{"-w"}, files...)
+	ctx.Logger.Debug(zbcontext.QuoteCommand("→ misspell", args))
+
+	ecmd := exec.Command("misspell", args...) // nosec
+	if out, err := ecmd.CombinedOutput(); err != nil {
+		ctx.Logger.WithError(err).WithField("linter", "misspell").Warn(strings.TrimSpace(string(out)))
+	}
+
+	var ret []string
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(data, before[file]) {
+			ret = append(ret, file)
+		}
+	}
+
+	return ret, nil
+}
+
+// ApplyFixes applies automatic fixes, for the gofmt, goimports and misspell linters,
+// to the files of the packages. Files with suffixes that are ignored are never
+// modified. The packages that had files modified are returned and have their
+// lint hash reset.
+func (l *ZBLint) ApplyFixes(ctx zbcontext.Context, pkgs project.Packages) (project.Packages, error) {
+	fixed := map[string][]string{}
+	var touched project.Packages
+
+	for _, pkg := range pkgs {
+		names := map[string]string{}
+
+		var files []string
+		for _, file := range lintFiles(pkg, l.NoTests) {
+			if l.fixable(file) {
+				files = append(files, file)
+			}
+		}
+
+		var changed bool
+
+		for _, file := range files {
+			fixes, err := l.fixFile(ctx, file, names)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(fixes) > 0 {
+				fixed[file] = append(fixed[file], fixes...)
+				changed = true
+			}
+		}
+
+		if l.LinterEnabled("misspell", true) {
+			files, err := misspell(ctx, files)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, file := range files {
+				fixed[file] = append(fixed[file], "misspell")
+				changed = true
+			}
+		}
+
+		if changed {
+			pkg.ClearLintHash()
+			touched.Insert(pkg)
+		}
+	}
+
+	var files []string
+	for file := range fixed {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		ctx.Logger.WithFields(slog.Fields{
+			"file":  file,
+			"fixes": strings.Join(fixed[file], ","),
+		}).Info("fixed")
+	}
+
+	ctx.Logger.WithFields(slog.Fields{
+		"files":    len(files),
+		"packages": len(touched),
+	}).Info("applied lint fixes")
+
+	return touched, nil
+}
@@ -0,0 +1,45 @@
+package zblint
+
+import (
+	"testing"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+func TestRemoveUnusedImports(t *testing.T) {
+	src := `package a
+
+import (
+	"fmt"
+	"strings"
+
+	"example.invalid/go-yaml"
+	"example.invalid/client.v4"
+)
+
+func f() {
+	fmt.Println(yamlv3.X, gitclient.Y)
+}
+`
+
+	// the imports that can't be found are used under names that differ from
+	// the ones their import paths suggest, so they are kept
+	want := `package a
+
+import (
+	"fmt"
+
+	"example.invalid/go-yaml"
+	"example.invalid/client.v4"
+)
+
+func f() {
+	fmt.Println(yamlv3.X, gitclient.Y)
+}
+`
+
+	got := removeUnusedImports(zbcontext.Context{}, "a.go", []byte(src), map[string]string{})
+	if string(got) != want {
+		t.Errorf("removeUnusedImports =\n%s\nwant\n%s", got, want)
+	}
+}
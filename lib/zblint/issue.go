@@ -0,0 +1,215 @@
+package zblint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"jrubin.io/zb/lib/lintflags"
+)
+
+// Issue is a single problem reported by a linter
+type Issue struct {
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Linter   string `json:"linter"`
+	Cached   bool   `json:"cached,omitempty"`
+}
+
+func (i Issue) String() string {
+	var col string
+	if i.Col > 0 {
+		col = strconv.Itoa(i.Col)
+	}
+
+	return fmt.Sprintf("%s:%d:%s:%s: %s (%s)", i.Path, i.Line, col, i.Severity, i.Message, i.Linter)
+}
+
+// ParseIssue parses a line of the form file:line:col:severity: message (linter)
+func ParseIssue(line string) (Issue, bool) {
+	m := levelRE.FindStringSubmatch(strings.TrimSuffix(line, "\n"))
+	if m == nil {
+		return Issue{}, false
+	}
+
+	i := Issue{
+		Path:     m[LintFile],
+		Severity: m[LintLevel],
+		Message:  m[LintMessage],
+		Linter:   m[LintLinter],
+	}
+
+	i.Line, _ = strconv.Atoi(m[LintLine])
+	i.Col, _ = strconv.Atoi(m[LintColumn])
+
+	return i, true
+}
+
+// Result is the outcome of linting a single package
+type Result struct {
+	Issues []Issue `json:"issues"`
+
+	// Output contains any lines written by the linter that are not issues
+	Output string `json:"output,omitempty"`
+}
+
+// ReadResult parses lint output from the Reader
+func ReadResult(pr io.Reader) (*Result, error) {
+	var res Result
+	var output bytes.Buffer
+
+	r := bufio.NewReader(pr)
+	for eof := false; !eof; {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			eof = true
+		} else if err != nil {
+			return nil, err
+		}
+
+		if line == "" {
+			continue
+		}
+
+		if i, ok := ParseIssue(line); ok {
+			res.Issues = append(res.Issues, i)
+			continue
+		}
+
+		output.WriteString(line)
+	}
+
+	res.Output = output.String()
+
+	return &res, nil
+}
+
+// Output formats
+const (
+	FormatText       = "text"
+	FormatJSON       = "json"
+	FormatCheckstyle = "checkstyle"
+)
+
+// OutputFormat returns the format that issues will be written in
+func (l *ZBLint) OutputFormat() string {
+	switch {
+	case l.Checkstyle:
+		return FormatCheckstyle
+	case l.JSON:
+		return FormatJSON
+	}
+	return FormatText
+}
+
+// WriteIssues writes the issues to the Writer in the configured output format
+func (l *ZBLint) WriteIssues(w io.Writer, issues []Issue) error {
+	switch l.OutputFormat() {
+	case FormatCheckstyle:
+		return writeCheckstyle(w, issues)
+	case FormatJSON:
+		return writeJSON(w, issues)
+	}
+
+	return l.writeTemplate(w, issues)
+}
+
+func (l *ZBLint) template() (*template.Template, error) {
+	format := l.Format
+	if format == "" {
+		format = lintflags.DefaultFormat
+	}
+
+	return template.New("format").Parse(format)
+}
+
+func (l *ZBLint) writeTemplate(w io.Writer, issues []Issue) error {
+	tmpl, err := l.template()
+	if err != nil {
+		return err
+	}
+
+	for _, i := range issues {
+		if err = tmpl.Execute(w, i); err != nil {
+			return err
+		}
+
+		if _, err = fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeJSON(w io.Writer, issues []Issue) error {
+	if issues == nil {
+		issues = []Issue{}
+	}
+
+	data, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+type checkstyleOutput struct {
+	XMLName xml.Name          `xml:"checkstyle"`
+	Version string            `xml:"version,attr"`
+	Files   []*checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Column   int    `xml:"column,attr"`
+	Line     int    `xml:"line,attr"`
+	Message  string `xml:"message,attr"`
+	Severity string `xml:"severity,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+func writeCheckstyle(w io.Writer, issues []Issue) error {
+	out := checkstyleOutput{Version: "5.0"}
+	files := map[string]*checkstyleFile{}
+
+	for _, i := range issues {
+		f, ok := files[i.Path]
+		if !ok {
+			f = &checkstyleFile{Name: i.Path}
+			files[i.Path] = f
+			out.Files = append(out.Files, f)
+		}
+
+		f.Errors = append(f.Errors, checkstyleError{
+			Column:   i.Col,
+			Line:     i.Line,
+			Message:  i.Message,
+			Severity: i.Severity,
+			Source:   i.Linter,
+		})
+	}
+
+	data, err := xml.MarshalIndent(&out, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
@@ -0,0 +1,76 @@
+package zblint
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIssue(t *testing.T) {
+	tests := []struct {
+		line string
+		want Issue
+		ok   bool
+	}{
+		{
+			"a/a.go:12:5:warning: exported function Foo should have comment or be unexported (golint)\n",
+			Issue{Path: "a/a.go", Line: 12, Col: 5, Severity: "warning", Message: "exported function Foo should have comment or be unexported", Linter: "golint"},
+			true,
+		},
+		{
+			"a/a.go:3::error: unreachable code (vet)",
+			Issue{Path: "a/a.go", Line: 3, Severity: "error", Message: "unreachable code", Linter: "vet"},
+			true,
+		},
+		{
+			// only the last parenthesized word is the linter
+			"b.go:1:2:warning: error return value not checked (f.Close()) (errcheck)",
+			Issue{Path: "b.go", Line: 1, Col: 2, Severity: "warning", Message: "error return value not checked (f.Close())", Linter: "errcheck"},
+			true,
+		},
+		{"a/a.go:12:5: missing severity (golint)", Issue{}, false},
+		{"a/a.go:12:5:warning: missing linter", Issue{}, false},
+		{"# example.com/a", Issue{}, false},
+	}
+
+	for _, test := range tests {
+		got, ok := ParseIssue(test.line)
+		if ok != test.ok || got != test.want {
+			t.Errorf("ParseIssue(%q) = %+v, %v, want %+v, %v", test.line, got, ok, test.want, test.ok)
+		}
+
+		// issues survive being written and parsed again
+		if ok {
+			if again, _ := ParseIssue(got.String()); again != got {
+				t.Errorf("ParseIssue(%q) = %+v, want %+v", got.String(), again, got)
+			}
+		}
+	}
+}
+
+func TestReadResult(t *testing.T) {
+	out := strings.Join([]string{
+		"a.go:1:1:warning: first (golint)",
+		"some other output",
+		"b.go:2::error: second (vet)",
+		"trailing",
+	}, "\n")
+
+	res, err := ReadResult(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Issue{
+		{Path: "a.go", Line: 1, Col: 1, Severity: "warning", Message: "first", Linter: "golint"},
+		{Path: "b.go", Line: 2, Severity: "error", Message: "second", Linter: "vet"},
+	}
+
+	if !reflect.DeepEqual(res.Issues, want) {
+		t.Errorf("issues = %+v, want %+v", res.Issues, want)
+	}
+
+	if res.Output != "some other output\ntrailing" {
+		t.Errorf("output = %q", res.Output)
+	}
+}
@@ -0,0 +1,407 @@
+package zblint
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"jrubin.io/zb/lib/lintflags"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// linter is a single check run by the builtin backend. Either check is set,
+// for linters that run in process, or command and args are, for linters that
+// are executed.
+type linter struct {
+	name     string
+	enabled  bool // whether the linter is enabled by default
+	fast     bool
+	severity string
+
+	check func(ctx zbcontext.Context, files []string) ([]Issue, error)
+
+	command string
+	args    func(f *lintflags.Data, dirs, files []string) []string
+	pattern *regexp.Regexp // defaults to defaultPattern
+
+	// message, if set, is expanded with the named groups of the pattern to
+	// create the issue message
+	message string
+}
+
+var (
+	defaultPattern = regexp.MustCompile(`\A(?:vet: )?(?P<path>[^\s:]+\.go):(?P<line>\d+):(?:(?P<col>\d+):)?\s*(?P<message>.+)\z`)
+	expandRE       = regexp.MustCompile(`\{(\w+)\}`)
+)
+
+var builtinLinters = []linter{{
+	name:     "gofmt",
+	enabled:  true,
+	fast:     true,
+	severity: SeverityWarning,
+	check:    checkGofmt,
+}, {
+	name:     "goimports",
+	enabled:  true,
+	fast:     true,
+	severity: SeverityWarning,
+	check:    checkGoimports,
+}, {
+	name:     "vet",
+	enabled:  true,
+	fast:     true,
+	severity: SeverityError,
+	command:  "go",
+	args: func(_ *lintflags.Data, dirs, _ []string) []string {
+		return append([]string{"vet"}, pkgArgs(dirs)...)
+	},
+}, {
+	name:     "golint",
+	enabled:  true,
+	fast:     true,
+	severity: SeverityWarning,
+	command:  "golint",
+	args: func(f *lintflags.Data, dirs, _ []string) []string {
+		return append([]string{"-min_confidence", fmt.Sprintf("%f", f.MinConfidence)}, dirs...)
+	},
+}, {
+	name:     "errcheck",
+	enabled:  true,
+	severity: SeverityWarning,
+	command:  "errcheck",
+	args: func(f *lintflags.Data, dirs, _ []string) []string {
+		args := []string{"-abspath"}
+		if f.NoTests {
+			args = append(args, "-ignoretests")
+		}
+		return append(args, pkgArgs(dirs)...)
+	},
+	message: "error return value not checked ({message})",
+}, {
+	name:     "ineffassign",
+	enabled:  true,
+	fast:     true,
+	severity: SeverityWarning,
+	command:  "ineffassign",
+	args: func(_ *lintflags.Data, dirs, _ []string) []string {
+		return pkgArgs(dirs)
+	},
+}, {
+	name:     "misspell",
+	enabled:  true,
+	fast:     true,
+	severity: SeverityWarning,
+	command:  "misspell",
+	args: func(_ *lintflags.Data, _, files []string) []string {
+		return files
+	},
+}, {
+	name:     "staticcheck",
+	enabled:  true,
+	severity: SeverityWarning,
+	command:  "staticcheck",
+	args: func(f *lintflags.Data, dirs, _ []string) []string {
+		return append([]string{fmt.Sprintf("-tests=%t", !f.NoTests)}, pkgArgs(dirs)...)
+	},
+}, {
+	name:     "unconvert",
+	enabled:  true,
+	severity: SeverityWarning,
+	command:  "unconvert",
+	args: func(_ *lintflags.Data, dirs, _ []string) []string {
+		return pkgArgs(dirs)
+	},
+}, {
+	name:     "goconst",
+	enabled:  true,
+	fast:     true,
+	severity: SeverityWarning,
+	command:  "goconst",
+	args: func(f *lintflags.Data, dirs, _ []string) []string {
+		return append([]string{
+			"-min-occurrences", strconv.Itoa(f.MinOccurrences),
+			"-min-length", strconv.Itoa(f.MinConstLength),
+		}, dirs...)
+	},
+}, {
+	name:     "gocyclo",
+	fast:     true,
+	severity: SeverityWarning,
+	command:  "gocyclo",
+	args: func(f *lintflags.Data, _, files []string) []string {
+		return append([]string{"-over", strconv.Itoa(f.CycloOver)}, files...)
+	},
+	pattern: regexp.MustCompile(`\A(?P<cyclo>\d+)\s+\S+\s+(?P<function>\S+)\s+(?P<path>[^\s:]+\.go):(?P<line>\d+):(?P<col>\d+)\z`),
+	message: "cyclomatic complexity {cyclo} of function {function}() is high",
+}, {
+	name:     "lll",
+	fast:     true,
+	severity: SeverityWarning,
+	command:  "lll",
+	args: func(f *lintflags.Data, _, files []string) []string {
+		return append([]string{"-l", strconv.Itoa(f.LineLength)}, files...)
+	},
+}, {
+	name:     "dupl",
+	severity: SeverityWarning,
+	command:  "dupl",
+	args: func(f *lintflags.Data, _, files []string) []string {
+		return append([]string{"-plumbing", "-threshold", strconv.Itoa(f.DuplThreshold)}, files...)
+	},
+	pattern: regexp.MustCompile(`\A(?P<path>[^\s:]+\.go):(?P<line>\d+)-\d+:\s*(?P<message>.+)\z`),
+}}
+
+// pkgArgs converts directories into a form that the go tool, and linters that
+// take package arguments, will treat as relative packages
+func pkgArgs(dirs []string) []string {
+	ret := make([]string, len(dirs))
+
+	for i, dir := range dirs {
+		if filepath.IsAbs(dir) || dir == "." || strings.HasPrefix(dir, "./") || strings.HasPrefix(dir, "../") {
+			ret[i] = dir
+			continue
+		}
+		ret[i] = "./" + filepath.ToSlash(dir)
+	}
+
+	return ret
+}
+
+// parse converts a line of linter output into an issue
+func (l linter) parse(line string) (Issue, bool) {
+	re := l.pattern
+	if re == nil {
+		re = defaultPattern
+	}
+
+	m := re.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Issue{}, false
+	}
+
+	groups := map[string]string{}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			groups[name] = m[i]
+		}
+	}
+
+	i := Issue{
+		Path:    groups["path"],
+		Message: groups["message"],
+		Linter:  groups["linter"],
+	}
+
+	if filepath.IsAbs(i.Path) {
+		if rel, err := filepath.Rel(zbcontext.CWD, i.Path); err == nil {
+			i.Path = rel
+		}
+	}
+	i.Path = filepath.Clean(i.Path)
+
+	i.Line, _ = strconv.Atoi(groups["line"])
+	i.Col, _ = strconv.Atoi(groups["col"])
+
+	if l.message != "" {
+		i.Message = expandRE.ReplaceAllStringFunc(l.message, func(s string) string {
+			return groups[s[1:len(s)-1]]
+		})
+	}
+
+	return i, true
+}
+
+// checkGofmt reports files whose formatting differs from that produced by
+// go/format. Files with syntax errors are left for vet to report.
+func checkGofmt(_ zbcontext.Context, files []string) ([]Issue, error) {
+	var issues []Issue
+
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := format.Source(src)
+		if err != nil || bytes.Equal(src, res) {
+			continue
+		}
+
+		issues = append(issues, Issue{
+			Path:    file,
+			Line:    firstDiff(src, res),
+			Message: "file is not gofmted",
+		})
+	}
+
+	return issues, nil
+}
+
+// firstDiff returns the first line that differs between a and b
+func firstDiff(a, b []byte) int {
+	al := bytes.Split(a, []byte("\n"))
+	bl := bytes.Split(b, []byte("\n"))
+
+	for i := 0; i < len(al) && i < len(bl); i++ {
+		if !bytes.Equal(al[i], bl[i]) {
+			return i + 1
+		}
+	}
+
+	if len(al) < len(bl) {
+		return len(al)
+	}
+
+	return len(bl)
+}
+
+// checkGoimports reports files that goimports would change, beyond what gofmt
+// would, because they have unused imports or standard library imports that
+// are not separated from other imports
+func checkGoimports(ctx zbcontext.Context, files []string) ([]Issue, error) {
+	// package names by source directory and import path
+	names := map[string]map[string]string{}
+
+	var issues []Issue
+	for _, file := range files {
+		fset := token.NewFileSet()
+
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			continue
+		}
+
+		srcDir := absPath(filepath.Dir(file))
+		if names[srcDir] == nil {
+			names[srcDir] = map[string]string{}
+		}
+
+		if line := importsLine(ctx, fset, f, srcDir, names[srcDir]); line > 0 {
+			issues = append(issues, Issue{
+				Path:    file,
+				Line:    line,
+				Message: "file is not goimported",
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// importsLine returns the first line with an import that goimports would
+// change, or 0 if there are none
+func importsLine(ctx zbcontext.Context, fset *token.FileSet, f *ast.File, srcDir string, names map[string]string) int {
+	var ret int
+
+	specs := append(unusedImports(ctx, f, srcDir, names), ungroupedImports(fset, f)...)
+	for _, spec := range specs {
+		if line := fset.Position(spec.Pos()).Line; ret == 0 || line < ret {
+			ret = line
+		}
+	}
+
+	return ret
+}
+
+// unusedImports returns the imports that are not referenced by the file
+func unusedImports(ctx zbcontext.Context, f *ast.File, srcDir string, names map[string]string) []*ast.ImportSpec {
+	used := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		// package qualified identifiers are not resolved by the parser
+		if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+			used[id.Name] = true
+		}
+
+		return true
+	})
+
+	var ret []*ast.ImportSpec
+
+	for _, spec := range f.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || importPath == "C" {
+			continue
+		}
+
+		var name string
+		if spec.Name != nil {
+			name = spec.Name.Name
+		} else {
+			name = packageName(ctx, importPath, srcDir, names)
+		}
+
+		// imports whose name can't be determined are assumed to be used
+		if name == "" || name == "_" || name == "." {
+			continue
+		}
+
+		if !used[name] {
+			ret = append(ret, spec)
+		}
+	}
+
+	return ret
+}
+
+// ungroupedImports returns the imports that goimports would separate from the
+// import before it because one is from the standard library and the other
+// isn't
+func ungroupedImports(fset *token.FileSet, f *ast.File) []*ast.ImportSpec {
+	var ret []*ast.ImportSpec
+
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+
+		for i := 1; i < len(gd.Specs); i++ {
+			prev := gd.Specs[i-1].(*ast.ImportSpec)
+			cur := gd.Specs[i].(*ast.ImportSpec)
+
+			adjacent := fset.Position(cur.Pos()).Line-fset.Position(prev.End()).Line <= 1
+			if adjacent && isStdlib(prev) != isStdlib(cur) {
+				ret = append(ret, cur)
+			}
+		}
+	}
+
+	return ret
+}
+
+func isStdlib(spec *ast.ImportSpec) bool {
+	importPath, _ := strconv.Unquote(spec.Path.Value)
+	first := strings.SplitN(importPath, "/", 2)[0]
+	return !strings.Contains(first, ".")
+}
+
+// packageName returns the name of the package with the given import path, or
+// "" if it can't be found. The name isn't guessed from the import path since
+// it often differs (e.g. gopkg.in/yaml.v2) and a wrong guess would make the
+// import look unused.
+func packageName(ctx zbcontext.Context, importPath, srcDir string, names map[string]string) string {
+	if name, ok := names[importPath]; ok {
+		return name
+	}
+
+	var name string
+	if pkg, err := ctx.Import(importPath, srcDir); err == nil {
+		name = pkg.Name
+	}
+
+	names[importPath] = name
+	return name
+}
@@ -0,0 +1,152 @@
+package zblint
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"jrubin.io/slog"
+	"jrubin.io/zb/lib/lintflags"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+func builtinLinter(t *testing.T, name string) linter {
+	t.Helper()
+
+	for _, l := range builtinLinters {
+		if l.name == name {
+			return l
+		}
+	}
+
+	t.Fatalf("no builtin linter named %s", name)
+	return linter{}
+}
+
+func TestLinterParse(t *testing.T) {
+	tests := []struct {
+		parser linter
+		line   string
+		want   Issue
+		ok     bool
+	}{
+		{
+			builtinLinter(t, "vet"),
+			"vet: a/a.go:10:2: unreachable code",
+			Issue{Path: "a/a.go", Line: 10, Col: 2, Message: "unreachable code"},
+			true,
+		},
+		{
+			builtinLinter(t, "golint"),
+			"a/a.go:3:1: exported function Foo should have comment or be unexported",
+			Issue{Path: "a/a.go", Line: 3, Col: 1, Message: "exported function Foo should have comment or be unexported"},
+			true,
+		},
+		{
+			builtinLinter(t, "errcheck"),
+			"a/a.go:7:12:	f.Close()",
+			Issue{Path: "a/a.go", Line: 7, Col: 12, Message: "error return value not checked (f.Close())"},
+			true,
+		},
+		{
+			builtinLinter(t, "lll"),
+			"./a/a.go:4: line is 130 characters",
+			Issue{Path: "a/a.go", Line: 4, Message: "line is 130 characters"},
+			true,
+		},
+		{
+			builtinLinter(t, "gocyclo"),
+			"12 a (*T).Run a/a.go:20:1",
+			Issue{Path: "a/a.go", Line: 20, Col: 1, Message: "cyclomatic complexity 12 of function (*T).Run() is high"},
+			true,
+		},
+		{
+			builtinLinter(t, "dupl"),
+			"a/a.go:5-20: duplicate of a/b.go:7-22",
+			Issue{Path: "a/a.go", Line: 5, Message: "duplicate of a/b.go:7-22"},
+			true,
+		},
+		{
+			builtinLinter(t, "vet"),
+			"# example.com/a",
+			Issue{},
+			false,
+		},
+		{
+			linter{pattern: golangciLintPattern},
+			"a/a.go:8:2: ineffectual assignment to x (ineffassign)",
+			Issue{Path: "a/a.go", Line: 8, Col: 2, Message: "ineffectual assignment to x", Linter: "ineffassign"},
+			true,
+		},
+		{
+			linter{pattern: staticcheckPattern, message: "{check}: {message}"},
+			"a/a.go:9:3: should use strings.Contains (S1003)",
+			Issue{Path: "a/a.go", Line: 9, Col: 3, Message: "S1003: should use strings.Contains"},
+			true,
+		},
+		{
+			linter{pattern: staticcheckPattern, message: "{check}: {message}"},
+			"a/a.go:9:3: no check",
+			Issue{},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		got, ok := test.parser.parse(test.line)
+		if ok != test.ok || got != test.want {
+			t.Errorf("%s.parse(%q) = %+v, %v, want %+v, %v", test.parser.name, test.line, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestExternalNormalize(t *testing.T) {
+	golangci := newGolangciLint(&lintflags.Data{}).(*external)
+
+	i := Issue{Linter: "typecheck", Severity: SeverityWarning}
+	golangci.normalize(&i)
+	if i.Severity != SeverityError {
+		t.Errorf("golangci-lint %s severity = %s, want %s", i.Linter, i.Severity, SeverityError)
+	}
+
+	i = Issue{Linter: "golint", Severity: SeverityWarning}
+	golangci.normalize(&i)
+	if i.Severity != SeverityWarning {
+		t.Errorf("golangci-lint %s severity = %s, want %s", i.Linter, i.Severity, SeverityWarning)
+	}
+
+	staticcheck := newStaticcheck(&lintflags.Data{}).(*external)
+
+	i = Issue{Message: "compile: undeclared name: x", Severity: SeverityWarning}
+	staticcheck.normalize(&i)
+	if i.Severity != SeverityError {
+		t.Errorf("staticcheck compile error severity = %s, want %s", i.Severity, SeverityError)
+	}
+}
+
+func TestBuiltinExecDeadline(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not installed")
+	}
+
+	l := linter{
+		name:    "slow",
+		command: "sleep",
+		args: func(*lintflags.Data, []string, []string) []string {
+			return []string{"5"}
+		},
+	}
+
+	dctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	b := builtin{Data: &lintflags.Data{}}
+	ctx := zbcontext.Context{Logger: slog.New()}
+
+	// the issues of a linter that didn't finish are incomplete and must not
+	// be cached as if the packages were clean
+	if issues, err := b.exec(dctx, ctx, l, nil, nil); err == nil {
+		t.Errorf("exec past the deadline = %v, want an error", issues)
+	}
+}
@@ -0,0 +1,214 @@
+package zblint
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"jrubin.io/slog"
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// nolintRE matches directives of the form
+// //zb:nolint[:linter,linter...] [// reason]
+var nolintRE = regexp.MustCompile(`\A//\s*zb:nolint(?::([\w,-]+))?\s*(?://\s*(.*?))?\s*\z`)
+
+// nolint is a directive that suppresses issues within a range of lines
+type nolint struct {
+	path     string // relative to the current working directory
+	line     int
+	from, to int
+	linters  map[string]bool // empty if all linters are suppressed
+	reason   string
+	used     bool
+}
+
+func (n *nolint) matches(i Issue) bool {
+	if i.Line < n.from || i.Line > n.to {
+		return false
+	}
+
+	return len(n.linters) == 0 || n.linters[i.Linter]
+}
+
+func parseNolint(text string) (*nolint, bool) {
+	m := nolintRE.FindStringSubmatch(text)
+	if m == nil {
+		return nil, false
+	}
+
+	n := nolint{
+		linters: map[string]bool{},
+		reason:  m[2],
+	}
+
+	for _, linter := range strings.Split(m[1], ",") {
+		if linter != "" {
+			n.linters[linter] = true
+		}
+	}
+
+	return &n, true
+}
+
+// declRange is a declaration, or spec within one, that a directive in its doc
+// comment, or on its first line, applies to
+type declRange struct {
+	doc        *ast.CommentGroup
+	start, end int
+}
+
+func declRanges(fset *token.FileSet, f *ast.File) []declRange {
+	var ret []declRange
+
+	add := func(doc *ast.CommentGroup, node ast.Node) {
+		ret = append(ret, declRange{
+			doc:   doc,
+			start: fset.Position(node.Pos()).Line,
+			end:   fset.Position(node.End()).Line,
+		})
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			add(d.Doc, d)
+		case *ast.GenDecl:
+			add(d.Doc, d)
+
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					add(s.Doc, s)
+				case *ast.ValueSpec:
+					add(s.Doc, s)
+				}
+			}
+		}
+	}
+
+	return ret
+}
+
+// fileNolints returns the directives in a file
+func fileNolints(path string) ([]*nolint, error) {
+	fset := token.NewFileSet()
+
+	f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := declRanges(fset, f)
+
+	// directives in the package doc comment, or on the package clause,
+	// apply to the whole file
+	pkgLine := fset.Position(f.Package).Line
+	fileEnd := fset.File(f.Pos()).LineCount()
+
+	var ret []*nolint
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			n, ok := parseNolint(c.Text)
+			if !ok {
+				continue
+			}
+
+			n.path = path
+			n.line = fset.Position(c.Pos()).Line
+			n.from, n.to = n.line, n.line
+
+			if cg == f.Doc || n.line == pkgLine {
+				n.from, n.to = 1, fileEnd
+			}
+
+			for _, r := range ranges {
+				if r.doc == cg || r.start == n.line {
+					if r.start < n.from {
+						n.from = r.start
+					}
+					if r.end > n.to {
+						n.to = r.end
+					}
+				}
+			}
+
+			ret = append(ret, n)
+		}
+	}
+
+	return ret, nil
+}
+
+func absPath(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(zbcontext.CWD, path)
+}
+
+// ApplyNolint removes the issues that are suppressed by //zb:nolint directives
+// in the source of the packages. With WarnNolint, warnings are logged for
+// directives that don't include a reason and for those that no longer
+// suppress any issue.
+func (l *ZBLint) ApplyNolint(ctx zbcontext.Context, issues []Issue, pkgs project.Packages) []Issue {
+	directives := map[string][]*nolint{}
+	var all []*nolint
+
+	for _, pkg := range pkgs {
+		for _, path := range lintFiles(pkg, l.NoTests) {
+			ns, err := fileNolints(path)
+			if err != nil {
+				// syntax errors will be reported by the linters
+				ctx.Logger.WithError(err).WithField("file", path).Debug("could not parse file for zb:nolint directives")
+				continue
+			}
+
+			directives[absPath(path)] = ns
+			all = append(all, ns...)
+		}
+	}
+
+	if len(all) == 0 {
+		return issues
+	}
+
+	var ret []Issue
+
+LOOP:
+	for _, i := range issues {
+		for _, n := range directives[absPath(i.Path)] {
+			if n.matches(i) {
+				n.used = true
+				continue LOOP
+			}
+		}
+
+		ret = append(ret, i)
+	}
+
+	if !l.WarnNolint {
+		return ret
+	}
+
+	for _, n := range all {
+		logger := ctx.Logger.WithFields(slog.Fields{
+			"file": n.path,
+			"line": n.line,
+		})
+
+		if n.reason == "" {
+			logger.Warn("zb:nolint directive has no reason")
+		}
+
+		if !n.used {
+			logger.Warn("zb:nolint directive does not suppress any issue")
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,189 @@
+package zblint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"jrubin.io/slog"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+func TestParseNolint(t *testing.T) {
+	tests := []struct {
+		text    string
+		linters []string
+		reason  string
+		ok      bool
+	}{
+		{"//zb:nolint", nil, "", true},
+		{"// zb:nolint:errcheck", []string{"errcheck"}, "", true},
+		{"//zb:nolint:errcheck,golint // the error is always nil", []string{"errcheck", "golint"}, "the error is always nil", true},
+		{"//zb:nolint // generated by a tool", nil, "generated by a tool", true},
+		{"//zb:nolintfoo", nil, "", false},
+		{"// nolint", nil, "", false},
+		{"/* zb:nolint */", nil, "", false},
+	}
+
+	for _, test := range tests {
+		n, ok := parseNolint(test.text)
+		if ok != test.ok {
+			t.Errorf("parseNolint(%q) ok = %v, want %v", test.text, ok, test.ok)
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		var linters []string
+		for linter := range n.linters {
+			linters = append(linters, linter)
+		}
+		sort.Strings(linters)
+
+		if !reflect.DeepEqual(linters, test.linters) || n.reason != test.reason {
+			t.Errorf("parseNolint(%q) = %v %q, want %v %q", test.text, linters, n.reason, test.linters, test.reason)
+		}
+	}
+}
+
+const nolintSrc = `package a
+
+import "os"
+
+// f does things
+//zb:nolint:errcheck // closing can't fail
+func f() {
+	os.Remove("x")
+}
+
+func g() { //zb:nolint
+	os.Remove("y")
+}
+
+var (
+	// x is suppressed
+	//zb:nolint:golint // legacy name
+	x_y = 1
+
+	z_w = 2
+)
+
+func h() {
+	os.Remove("z") //zb:nolint:errcheck
+	os.Remove("w")
+}
+`
+
+func TestFileNolints(t *testing.T) {
+	dir := testProject(t, map[string]string{
+		"a.go":    nolintSrc,
+		"file.go": "// Package a is generated\n//zb:nolint:lll // generated\npackage a\n\nvar long = 1\n",
+		"pkg.go":  "package a //zb:nolint:golint // all of it\n\nvar y_z = 1\n",
+	})
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	type rng struct{ line, from, to int }
+
+	tests := []struct {
+		file string
+		want []rng
+	}{
+		{"a.go", []rng{{6, 6, 9}, {11, 11, 13}, {17, 17, 18}, {24, 24, 24}}},
+		{"file.go", []rng{{2, 1, 5}}},
+		{"pkg.go", []rng{{1, 1, 3}}},
+	}
+
+	for _, test := range tests {
+		ns, err := fileNolints(filepath.Join(dir, test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []rng
+		for _, n := range ns {
+			got = append(got, rng{n.line, n.from, n.to})
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ranges of %s = %v, want %v", test.file, got, test.want)
+		}
+	}
+}
+
+type testHandler struct {
+	messages []string
+}
+
+func (h *testHandler) HandleLog(e *slog.Entry) error {
+	h.messages = append(h.messages, e.Message)
+	return nil
+}
+
+func TestApplyNolint(t *testing.T) {
+	dir := testProject(t, map[string]string{"a.go": nolintSrc})
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// the files of the package are linted relative to the working directory
+	cwd := zbcontext.CWD
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	zbcontext.CWD = dir
+	defer func() {
+		zbcontext.CWD = cwd
+		_ = os.Chdir(cwd)
+	}()
+
+	file := filepath.Join(dir, "a.go")
+	pkgs := testPackages(dir)
+	pkgs[0].GoFiles = []string{"a.go"}
+
+	issue := func(line int, linter string) Issue {
+		return Issue{Path: file, Line: line, Linter: linter, Severity: SeverityWarning}
+	}
+
+	issues := []Issue{
+		issue(8, "errcheck"),  // suppressed by the doc comment of f
+		issue(8, "golint"),    // f only suppresses errcheck
+		issue(12, "errcheck"), // g suppresses everything
+		issue(12, "golint"),
+		issue(20, "golint"),   // z_w isn't covered by the x_y directive
+		issue(24, "errcheck"), // same line
+		issue(25, "errcheck"), // next line
+	}
+
+	want := []Issue{issues[1], issues[4], issues[6]}
+
+	var h testHandler
+	logger := slog.New()
+	logger.RegisterHandler(slog.DebugLevel, &h)
+	ctx := zbcontext.Context{Logger: logger}
+
+	var l ZBLint
+	if got := l.ApplyNolint(ctx, issues, pkgs); !reflect.DeepEqual(got, want) {
+		t.Errorf("ApplyNolint = %+v, want %+v", got, want)
+	}
+
+	if len(h.messages) != 0 {
+		t.Errorf("unexpected warnings without WarnNolint: %v", h.messages)
+	}
+
+	l.WarnNolint = true
+	l.ApplyNolint(ctx, issues, pkgs)
+
+	// g has no reason, the x_y directive suppresses nothing and the h
+	// directive has no reason
+	wantMessages := []string{
+		"zb:nolint directive has no reason",
+		"zb:nolint directive does not suppress any issue",
+		"zb:nolint directive has no reason",
+	}
+
+	if !reflect.DeepEqual(h.messages, wantMessages) {
+		t.Errorf("warnings = %q, want %q", h.messages, wantMessages)
+	}
+}
@@ -0,0 +1,185 @@
+package zblint
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sergi/go-diff/diffmatchpatch"
+
+	git "srcd.works/go-git.v4"
+	"srcd.works/go-git.v4/plumbing"
+	"srcd.works/go-git.v4/plumbing/object"
+	"srcd.works/go-git.v4/utils/diff"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// resolveRevision returns the commit named by rev, which may be a commit hash,
+// HEAD, or the name of a branch, tag or remote branch
+func resolveRevision(repo *git.Repository, rev string) (*object.Commit, error) {
+	if len(rev) == 40 {
+		if _, err := hex.DecodeString(rev); err == nil {
+			return repo.Commit(plumbing.NewHash(rev))
+		}
+	}
+
+	names := []string{
+		rev,
+		"refs/heads/" + rev,
+		"refs/tags/" + rev,
+		"refs/remotes/" + rev,
+	}
+
+	for _, name := range names {
+		ref, err := repo.Reference(plumbing.ReferenceName(name), true)
+		if err != nil {
+			continue
+		}
+
+		if c, err := repo.Commit(ref.Hash()); err == nil {
+			return c, nil
+		}
+
+		// annotated tags point to a tag object rather than a commit
+		tag, err := repo.Tag(ref.Hash())
+		if err != nil {
+			continue
+		}
+
+		return tag.Commit()
+	}
+
+	return nil, errors.Errorf("unknown revision: %s", rev)
+}
+
+// revChanges computes the lines of files that were added or modified since a
+// revision
+type revChanges struct {
+	rev     string
+	commits map[string]*object.Commit // by project directory
+	lines   map[string]map[int]bool   // by absolute file path, nil if all lines are new
+}
+
+func newRevChanges(rev string) *revChanges {
+	return &revChanges{
+		rev:     rev,
+		commits: map[string]*object.Commit{},
+		lines:   map[string]map[int]bool{},
+	}
+}
+
+func (c *revChanges) commit(projectDir string) (*object.Commit, error) {
+	if commit, ok := c.commits[projectDir]; ok {
+		return commit, nil
+	}
+
+	repo, err := git.PlainOpen(projectDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening repository %s", projectDir)
+	}
+
+	commit, err := resolveRevision(repo, c.rev)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error resolving revision in %s", projectDir)
+	}
+
+	c.commits[projectDir] = commit
+	return commit, nil
+}
+
+func countLines(text string) int {
+	n := strings.Count(text, "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		n++
+	}
+	return n
+}
+
+// changed returns the lines of the file that differ from the revision
+func (c *revChanges) changed(projectDir, rel, abs string) (map[int]bool, error) {
+	if lines, ok := c.lines[abs]; ok {
+		return lines, nil
+	}
+
+	commit, err := c.commit(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines map[int]bool
+
+	file, err := commit.File(rel)
+	switch {
+	case err == object.ErrFileNotFound:
+		// the file is new, every line has changed
+	case err != nil:
+		return nil, err
+	default:
+		var old string
+		if old, err = file.Contents(); err != nil {
+			return nil, err
+		}
+
+		data, err := ioutil.ReadFile(abs)
+		if err != nil {
+			return nil, err
+		}
+
+		lines = map[int]bool{}
+		var line int
+
+		for _, d := range diff.Do(old, string(data)) {
+			n := countLines(d.Text)
+
+			switch d.Type {
+			case diffmatchpatch.DiffEqual:
+				line += n
+			case diffmatchpatch.DiffInsert:
+				for i := 1; i <= n; i++ {
+					lines[line+i] = true
+				}
+				line += n
+			}
+		}
+	}
+
+	c.lines[abs] = lines
+	return lines, nil
+}
+
+// FilterNewFromRev returns only the issues on lines that were added or
+// modified since the NewFromRev revision. Issues that are not associated with
+// a line are kept if their file has changed at all.
+func (l *ZBLint) FilterNewFromRev(ctx zbcontext.Context, issues []Issue) ([]Issue, error) {
+	if l.NewFromRev == "" {
+		return issues, nil
+	}
+
+	c := newRevChanges(l.NewFromRev)
+
+	var ret []Issue
+	for _, i := range issues {
+		projectDir, rel := projectPath(i.Path)
+		if projectDir == "" {
+			ctx.Logger.WithField("file", i.Path).Debug("file is not in a git repository, keeping lint issue")
+			ret = append(ret, i)
+			continue
+		}
+
+		abs := filepath.Join(projectDir, filepath.FromSlash(rel))
+
+		lines, err := c.changed(projectDir, rel, abs)
+		if err != nil {
+			return nil, err
+		}
+
+		if lines == nil || lines[i.Line] || (i.Line == 0 && len(lines) > 0) {
+			ret = append(ret, i)
+		}
+	}
+
+	return ret, nil
+}
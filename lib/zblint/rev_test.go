@@ -0,0 +1,102 @@
+package zblint
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"jrubin.io/slog"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"a\n", 1},
+		{"a\nb", 2},
+		{"\n\n", 2},
+	}
+
+	for _, test := range tests {
+		if got := countLines(test.text); got != test.want {
+			t.Errorf("countLines(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	args = append([]string{"-c", "user.name=zb", "-c", "user.email=zb@example.com", "-c", "commit.gpgsign=false"}, args...)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestFilterNewFromRev(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := testProject(t, map[string]string{
+		"a.go": "package a\n\nfunc f() {\n\tx := 1\n}\n",
+	})
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	if err := os.Remove(filepath.Join(dir, ".git")); err != nil {
+		t.Fatal(err)
+	}
+
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", "a.go")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+	runGit(t, dir, "tag", "base")
+
+	// line 3 is added, the old line 4, now 5, is modified and b.go is new
+	writeFile(t, filepath.Join(dir, "a.go"), "package a\n\n// f does things\nfunc f() {\n\tx := 2\n}\n")
+	writeFile(t, filepath.Join(dir, "b.go"), "package a\n\nvar y = 1\n")
+
+	aFile := filepath.Join(dir, "a.go")
+	bFile := filepath.Join(dir, "b.go")
+
+	issues := []Issue{
+		{Path: aFile, Line: 1, Linter: "golint", Message: "unchanged"},
+		{Path: aFile, Line: 3, Linter: "golint", Message: "added"},
+		{Path: aFile, Line: 4, Linter: "golint", Message: "moved but unchanged"},
+		{Path: aFile, Line: 5, Linter: "ineffassign", Message: "modified"},
+		{Path: aFile, Line: 0, Linter: "vet", Message: "no line in a changed file"},
+		{Path: bFile, Line: 3, Linter: "golint", Message: "new file"},
+	}
+
+	ctx := zbcontext.Context{Logger: slog.New()}
+
+	l := ZBLint{NewFromRev: "base"}
+	got, err := l.FilterNewFromRev(ctx, issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Issue{issues[1], issues[3], issues[4], issues[5]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterNewFromRev = %+v, want %+v", got, want)
+	}
+
+	l.NewFromRev = "missing"
+	if _, err = l.FilterNewFromRev(ctx, issues); err == nil {
+		t.Error("expected an error for an unknown revision")
+	}
+
+	l.NewFromRev = ""
+	if got, _ = l.FilterNewFromRev(ctx, issues); !reflect.DeepEqual(got, issues) {
+		t.Errorf("FilterNewFromRev without a revision = %+v, want all issues", got)
+	}
+}
@@ -0,0 +1,202 @@
+package zblint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifBaseID  = "SRCROOT"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "note"
+}
+
+// sarifBases assigns a uri base id to each project directory
+type sarifBases map[string]string
+
+func (b sarifBases) id(projectDir string) string {
+	if id, ok := b[projectDir]; ok {
+		return id
+	}
+
+	id := sarifBaseID
+	if len(b) > 0 {
+		id = fmt.Sprintf("%s%d", sarifBaseID, len(b)+1)
+	}
+
+	b[projectDir] = id
+	return id
+}
+
+// location returns the path of the issue relative to its project directory
+// and the uri base id of that directory
+func (b sarifBases) location(path string) (sarifArtifactLocation, string) {
+	projectDir, rel := projectPath(path)
+	if projectDir == "" {
+		return sarifArtifactLocation{URI: filepath.ToSlash(path)}, ""
+	}
+
+	return sarifArtifactLocation{
+		URI:       rel,
+		URIBaseID: b.id(projectDir),
+	}, projectDir
+}
+
+// WriteSARIF writes the issues to the Writer as a SARIF log with one run per
+// linter
+func WriteSARIF(w io.Writer, issues []Issue) error {
+	byLinter := map[string][]Issue{}
+	for _, i := range issues {
+		byLinter[i.Linter] = append(byLinter[i.Linter], i)
+	}
+
+	var linters []string
+	for linter := range byLinter {
+		linters = append(linters, linter)
+	}
+	sort.Strings(linters)
+
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{},
+	}
+
+	bases := sarifBases{}
+
+	for _, linter := range linters {
+		run := sarifRun{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:  linter,
+				Rules: []sarifRule{{ID: linter, Name: linter}},
+			}},
+			OriginalURIBaseIDs: map[string]sarifArtifactLocation{},
+			Results:            []sarifResult{},
+		}
+
+		for _, i := range byLinter[linter] {
+			loc, projectDir := bases.location(i.Path)
+			if projectDir != "" {
+				run.OriginalURIBaseIDs[loc.URIBaseID] = sarifArtifactLocation{
+					URI: "file://" + filepath.ToSlash(projectDir) + "/",
+				}
+			}
+
+			pl := sarifPhysicalLocation{ArtifactLocation: loc}
+			if i.Line > 0 {
+				pl.Region = &sarifRegion{
+					StartLine:   i.Line,
+					StartColumn: i.Col,
+				}
+			}
+
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    linter,
+				Level:     sarifLevel(i.Severity),
+				Message:   sarifMessage{Text: i.Message},
+				Locations: []sarifLocation{{PhysicalLocation: pl}},
+			})
+		}
+
+		log.Runs = append(log.Runs, run)
+	}
+
+	data, err := json.MarshalIndent(&log, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// SaveSARIF writes the issues as a SARIF log to the file given by the SARIF
+// option, if it is set
+func (l *ZBLint) SaveSARIF(issues []Issue) error {
+	if l.SARIF == "" {
+		return nil
+	}
+
+	fd, err := os.Create(l.SARIF)
+	if err != nil {
+		return err
+	}
+
+	if err = WriteSARIF(fd, issues); err != nil {
+		_ = fd.Close() // nosec
+		return err
+	}
+
+	return fd.Close()
+}
@@ -0,0 +1,131 @@
+package zblint
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	dir := testProject(t, nil)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	outside, err := ioutil.TempDir("", "zblint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(outside) }()
+
+	issues := []Issue{
+		{Path: filepath.Join(dir, "a", "a.go"), Line: 4, Col: 2, Severity: SeverityError, Message: "unreachable code", Linter: "vet"},
+		{Path: filepath.Join(dir, "b.go"), Line: 1, Severity: SeverityWarning, Message: "exported var y should have comment", Linter: "golint"},
+		{Path: filepath.Join(outside, "c.go"), Severity: "info", Message: "no line", Linter: "golint"},
+	}
+
+	var buf bytes.Buffer
+	if err = WriteSARIF(&buf, issues); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]interface{}
+	if err = json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	base := "file://" + filepath.ToSlash(dir) + "/"
+
+	want := map[string]interface{}{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool": map[string]interface{}{"driver": map[string]interface{}{
+					"name":  "golint",
+					"rules": []interface{}{map[string]interface{}{"id": "golint", "name": "golint"}},
+				}},
+				"originalUriBaseIds": map[string]interface{}{
+					"SRCROOT": map[string]interface{}{"uri": base},
+				},
+				"results": []interface{}{
+					map[string]interface{}{
+						"ruleId":    "golint",
+						"ruleIndex": 0.0,
+						"level":     "warning",
+						"message":   map[string]interface{}{"text": "exported var y should have comment"},
+						"locations": []interface{}{map[string]interface{}{"physicalLocation": map[string]interface{}{
+							"artifactLocation": map[string]interface{}{"uri": "b.go", "uriBaseId": "SRCROOT"},
+							"region":           map[string]interface{}{"startLine": 1.0},
+						}}},
+					},
+					map[string]interface{}{
+						"ruleId":    "golint",
+						"ruleIndex": 0.0,
+						"level":     "note",
+						"message":   map[string]interface{}{"text": "no line"},
+						"locations": []interface{}{map[string]interface{}{"physicalLocation": map[string]interface{}{
+							"artifactLocation": map[string]interface{}{"uri": filepath.ToSlash(filepath.Join(outside, "c.go"))},
+						}}},
+					},
+				},
+			},
+			map[string]interface{}{
+				"tool": map[string]interface{}{"driver": map[string]interface{}{
+					"name":  "vet",
+					"rules": []interface{}{map[string]interface{}{"id": "vet", "name": "vet"}},
+				}},
+				"originalUriBaseIds": map[string]interface{}{
+					"SRCROOT": map[string]interface{}{"uri": base},
+				},
+				"results": []interface{}{
+					map[string]interface{}{
+						"ruleId":    "vet",
+						"ruleIndex": 0.0,
+						"level":     "error",
+						"message":   map[string]interface{}{"text": "unreachable code"},
+						"locations": []interface{}{map[string]interface{}{"physicalLocation": map[string]interface{}{
+							"artifactLocation": map[string]interface{}{"uri": "a/a.go", "uriBaseId": "SRCROOT"},
+							"region":           map[string]interface{}{"startLine": 4.0, "startColumn": 2.0},
+						}}},
+					},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteSARIF =\n%s", buf.String())
+	}
+
+	// an empty log still has a runs array
+	buf.Reset()
+	if err = WriteSARIF(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var empty struct {
+		Runs []interface{} `json:"runs"`
+	}
+	if err = json.Unmarshal(buf.Bytes(), &empty); err != nil || empty.Runs == nil {
+		t.Errorf("WriteSARIF of no issues = %s", buf.String())
+	}
+}
+
+func TestSARIFBases(t *testing.T) {
+	b := sarifBases{}
+
+	if id := b.id("/a"); id != "SRCROOT" {
+		t.Errorf("first id = %s, want SRCROOT", id)
+	}
+
+	if id := b.id("/b"); id != "SRCROOT2" {
+		t.Errorf("second id = %s, want SRCROOT2", id)
+	}
+
+	if id := b.id("/a"); id != "SRCROOT" {
+		t.Errorf("repeated id = %s, want SRCROOT", id)
+	}
+}
@@ -0,0 +1,139 @@
+package zblint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"jrubin.io/zb/lib/project"
+)
+
+// topFiles is the number of files included in a Summary
+const topFiles = 10
+
+// Count is the number of issues attributed to Name
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Summary of lint issues
+type Summary struct {
+	Total      int     `json:"total"`
+	Linters    []Count `json:"linters"`
+	Severities []Count `json:"severities"`
+	Packages   []Count `json:"packages"`
+	TopFiles   []Count `json:"top_files"`
+}
+
+func counts(m map[string]int, top int) []Count {
+	ret := []Count{}
+	for name, count := range m {
+		ret = append(ret, Count{Name: name, Count: count})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			return ret[i].Count > ret[j].Count
+		}
+		return ret[i].Name < ret[j].Name
+	})
+
+	if top > 0 && len(ret) > top {
+		ret = ret[:top]
+	}
+
+	return ret
+}
+
+// Summarize counts the issues by linter, severity, package and file. Issues
+// are attributed to the package in the directory of their file.
+func Summarize(issues []Issue, pkgs project.Packages) *Summary {
+	importPaths := map[string]string{}
+	for _, pkg := range pkgs {
+		importPaths[absPath(pkg.Package.Dir)] = pkg.ImportPath
+	}
+
+	linters := map[string]int{}
+	severities := map[string]int{}
+	packages := map[string]int{}
+	files := map[string]int{}
+
+	for _, i := range issues {
+		linters[i.Linter]++
+		severities[i.Severity]++
+		files[i.Path]++
+
+		dir := filepath.Dir(i.Path)
+		if importPath, ok := importPaths[absPath(dir)]; ok {
+			dir = importPath
+		}
+		packages[dir]++
+	}
+
+	return &Summary{
+		Total:      len(issues),
+		Linters:    counts(linters, 0),
+		Severities: counts(severities, 0),
+		Packages:   counts(packages, 0),
+		TopFiles:   counts(files, topFiles),
+	}
+}
+
+func writeCounts(w io.Writer, title string, cs []Count) error {
+	fmt.Fprintf(w, "%s\n", title)
+
+	if len(cs) == 0 {
+		fmt.Fprintf(w, "  (none)\n\n")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, c := range cs {
+		fmt.Fprintf(tw, "  %d\t%s\n", c.Count, c.Name)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	return nil
+}
+
+// Write the summary as tables
+func (s *Summary) Write(w io.Writer) error {
+	fmt.Fprintf(w, "\nTOTAL ISSUES: %d\n\n", s.Total)
+
+	sections := []struct {
+		title string
+		cs    []Count
+	}{
+		{"BY LINTER", s.Linters},
+		{"BY SEVERITY", s.Severities},
+		{"BY PACKAGE", s.Packages},
+		{"TOP FILES", s.TopFiles},
+	}
+
+	for _, section := range sections {
+		if err := writeCounts(w, section.title, section.cs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes the summary as JSON
+func (s *Summary) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
@@ -1,19 +1,22 @@
 package zblint
 
 import (
-	"bufio"
-	"bytes"
+	"encoding/json"
 	"fmt"
-	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/urfave/cli"
 
+	"jrubin.io/zb/lib/generated"
 	"jrubin.io/zb/lib/lintflags"
 	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbconfig"
 	"jrubin.io/zb/lib/zbcontext"
 )
 
@@ -22,6 +25,15 @@ type ZBLint struct {
 	lintflags.Data
 	NoMissingComment bool
 	IgnoreSuffixes   cli.StringSlice
+	SARIF            string
+	Baseline         bool
+	WriteBaseline    bool
+	NewFromRev       string
+	Fix              bool
+	Summary          bool
+	SummaryJSON      bool
+	LintGenerated    bool
+	WarnNolint       bool
 
 	ignoreSuffixMap map[string]struct{}
 }
@@ -60,6 +72,47 @@ func (l *ZBLint) LintSetup(ctx zbcontext.Context) zbcontext.Context {
 	return ctx
 }
 
+// ResolveBackend selects the backend that lints the packages. The flag takes
+// precedence over the configuration of the projects that the packages belong
+// to, which must not configure different backends. It must be called before
+// the packages' results are looked up since the backend is part of their
+// hash.
+func (l *ZBLint) ResolveBackend(pkgs project.Packages) error {
+	if l.Data.Backend != "" && l.Data.Backend != lintflags.BackendAuto {
+		return nil
+	}
+
+	var configured, configuredBy string
+	seen := map[string]bool{}
+
+	for _, pkg := range pkgs {
+		dir := zbcontext.GitDir(pkg.Dir)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		config, err := zbconfig.Get(dir)
+		if err != nil {
+			return err
+		}
+
+		backend := config.LintBackend()
+		if backend == "" || backend == configured {
+			continue
+		}
+
+		if configured != "" {
+			return errors.Errorf("%s and %s configure different lint backends (%s and %s), select one with --backend", configuredBy, dir, configured, backend)
+		}
+
+		configured, configuredBy = backend, dir
+	}
+
+	l.Data.Backend = resolveBackend(configured)
+	return nil
+}
+
 // CacheFile returns the location of the lint cache file for a given package
 func (l *ZBLint) CacheFile(ctx zbcontext.Context, p *project.Package) (string, error) {
 	lintHash, err := p.LintHash(&l.Data)
@@ -70,7 +123,7 @@ func (l *ZBLint) CacheFile(ctx zbcontext.Context, p *project.Package) (string, e
 	return filepath.Join(
 		ctx.CacheDir,
 		lintHash[:3],
-		fmt.Sprintf("%s.lint", lintHash[3:]),
+		fmt.Sprintf("%s.json", lintHash[3:]),
 	), nil
 }
 
@@ -89,26 +142,41 @@ func (l *ZBLint) HaveResult(ctx zbcontext.Context, p *project.Package) (bool, er
 	return err == nil && fi.Mode().IsRegular(), nil
 }
 
-// ReadResult reads lint results from the Reader and writes the unfiltered data
-// to the file and the filtered data to the Writer
-func (l *ZBLint) ReadResult(w io.Writer, pr io.Reader, file string) error {
-	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+// SaveResult writes the result to the cache file
+func (l *ZBLint) SaveResult(file string, res *Result) error {
+	data, err := json.Marshal(res)
+	if err != nil {
 		return err
 	}
 
-	fd, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
+	if err = os.MkdirAll(filepath.Dir(file), 0700); err != nil {
 		return err
 	}
 
-	defer func() { _ = fd.Close() }() // nosec
+	return ioutil.WriteFile(file, data, 0600)
+}
+
+// LoadResult reads a result from the cache file
+func (l *ZBLint) LoadResult(file string) (*Result, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var res Result
+	if err = json.Unmarshal(data, &res); err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", file)
+	}
+
+	for i := range res.Issues {
+		res.Issues[i].Cached = true
+	}
 
-	_, err = l.readCommon(w, pr, fd)
-	return err
+	return &res, nil
 }
 
 var (
-	levelRE   = regexp.MustCompile(`\A([^:]*):(\d*):(\d*):(\w+): (.*?) \((\w+)\)( \(cached\))?\n\z`)
+	levelRE   = regexp.MustCompile(`\A([^:]*):(\d*):(\d*):(\w+): (.*?) \((\w+)\)\z`)
 	commentRE = regexp.MustCompile(` should have comment.* or be unexported`)
 )
 
@@ -125,73 +193,87 @@ const (
 	LintLinter
 )
 
-func (l *ZBLint) readCommon(w io.Writer, pr io.Reader, fd io.Writer) (bool, error) {
-	r := bufio.NewReader(pr)
-	defer func() { _, _ = io.Copy(w, r) }() // nosec
+// lintFiles returns the go files of the package that should be linted,
+// relative to the current working directory
+func lintFiles(pkg *project.Package, noTests bool) []string {
+	var names []string
+	names = append(names, pkg.GoFiles...)
+	names = append(names, pkg.CgoFiles...)
 
-	var buf bytes.Buffer
-	var foundLines bool
+	if !noTests {
+		names = append(names, pkg.TestGoFiles...)
+		names = append(names, pkg.XTestGoFiles...)
+	}
 
-LOOP:
-	for eof := false; !eof; {
-		line, err := r.ReadString('\n')
-		if err == io.EOF {
-			eof = true
-		} else if err != nil {
-			return foundLines, err
-		}
+	dir := relDir(pkg)
 
-		m := levelRE.FindStringSubmatch(line)
-		if m == nil {
-			if fd != nil {
-				fmt.Fprintf(&buf, "%s", line)
-			}
-			if _, err := w.Write([]byte(line)); err != nil {
-				return foundLines, err
-			}
-			continue
-		}
+	files := make([]string, len(names))
+	for i, name := range names {
+		files[i] = filepath.Join(dir, name)
+	}
+
+	sort.Strings(files)
 
-		foundLines = true
+	return files
+}
 
-		if fd != nil {
-			fmt.Fprintf(&buf, "%s (cached)\n", strings.TrimSuffix(line, "\n"))
+// sortIssues orders issues by their position and then, if it is set, by key
+func sortIssues(issues []Issue, key lintflags.SortKey) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		x, y := issues[i], issues[j]
+		if x.Path != y.Path {
+			return x.Path < y.Path
 		}
+		if x.Line != y.Line {
+			return x.Line < y.Line
+		}
+		if x.Col != y.Col {
+			return x.Col < y.Col
+		}
+		return x.Linter < y.Linter
+	})
+
+	var fn func(i Issue) string
+	switch key {
+	case lintflags.SortSeverity:
+		fn = func(i Issue) string { return i.Severity }
+	case lintflags.SortMessage:
+		fn = func(i Issue) string { return i.Message }
+	case lintflags.SortLinter:
+		fn = func(i Issue) string { return i.Linter }
+	default:
+		return
+	}
 
+	sort.SliceStable(issues, func(i, j int) bool {
+		return fn(issues[i]) < fn(issues[j])
+	})
+}
+
+// Filter returns the issues that should be shown
+func (l *ZBLint) Filter(issues []Issue) []Issue {
+	var ret []Issue
+
+LOOP:
+	for _, i := range issues {
 		if l.NoMissingComment &&
-			m[LintLinter] == "golint" &&
-			commentRE.MatchString(m[LintMessage]) {
+			i.Linter == "golint" &&
+			commentRE.MatchString(i.Message) {
 			continue
 		}
 
 		for is := range l.ignoreSuffixMap {
-			if strings.HasSuffix(m[LintFile], is) {
+			if strings.HasSuffix(i.Path, is) {
 				continue LOOP
 			}
 		}
 
-		if _, err := w.Write([]byte(line)); err != nil {
-			return foundLines, err
-		}
-	}
-
-	if fd != nil {
-		if _, err := buf.WriteTo(fd); err != nil {
-			return foundLines, err
+		if !l.LintGenerated && generated.File(absPath(i.Path)) {
+			continue
 		}
-	}
-
-	return foundLines, nil
-}
 
-// ShowResult reads data from cacheFile and writes the filtered data to the
-// Writer
-func (l *ZBLint) ShowResult(w io.Writer, cacheFile string) (bool, error) {
-	fd, err := os.Open(cacheFile)
-	if err != nil {
-		return false, err
+		ret = append(ret, i)
 	}
-	defer func() { _ = fd.Close() }() // nosec
 
-	return l.readCommon(w, fd, nil)
+	return ret
 }
@@ -0,0 +1,140 @@
+package zbtest
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+
+	"golang.org/x/sync/errgroup"
+
+	"jrubin.io/zb/lib/project"
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// ResultCacher is implemented by the types that cache the output of go test
+// for each package
+type ResultCacher interface {
+	HaveResult(ctx zbcontext.Context, p *project.Package) (bool, error)
+}
+
+// PackagesLists returns the non-vendored packages of in that include reports
+// true for, or all of them if it is nil, and those of them that don't have a
+// cached result
+func PackagesLists(ctx zbcontext.Context, c ResultCacher, in project.Packages, include func(*project.Package) bool) (pkgs, toRun project.Packages, err error) {
+	for _, pkg := range in {
+		if pkg.IsVendored || (include != nil && !include(pkg)) {
+			continue
+		}
+
+		pkgs.Insert(pkg)
+
+		var foundResult bool
+		if foundResult, err = c.HaveResult(ctx, pkg); err != nil {
+			return
+		}
+
+		if !foundResult {
+			toRun.Insert(pkg)
+		}
+	}
+
+	return
+}
+
+// ProjectsLists is like PackagesLists for the packages of each of the projects
+func ProjectsLists(ctx zbcontext.Context, c ResultCacher, projects project.List, include func(*project.Package) bool) (pkgs, toRun project.Packages, err error) {
+	for _, proj := range projects {
+		var p, r project.Packages
+		p, r, err = PackagesLists(ctx, c, proj.Packages, include)
+		if err != nil {
+			return
+		}
+
+		pkgs = pkgs.Append(p)
+		toRun = toRun.Append(r)
+	}
+
+	return
+}
+
+// ResultFunc is called by Exec for each package in order. r is the output of
+// go test, positioned at the start of the package's output, if the package
+// was run and nil if its cached result should be shown instead.
+type ResultFunc func(pkg *project.Package, r StringReader) error
+
+// Exec runs go test with args, and env if it is not nil, on the toRun
+// packages, copying its output to w, and calls fn for each of the pkgs, which
+// must include toRun in the same order. It returns the exit code of go test.
+func Exec(ctx zbcontext.Context, w io.Writer, args, env []string, pkgs, toRun project.Packages, fn ResultFunc) (int, error) {
+	var ecmd *exec.Cmd
+	pr, pw := io.Pipe()
+	if len(toRun) > 0 {
+		if err := os.MkdirAll(ctx.CacheDir, 0700); err != nil {
+			return 0, err
+		}
+
+		args = append([]string{"test"}, args...)
+		for _, pkg := range toRun {
+			args = append(args, pkg.ImportPath)
+		}
+
+		ctx.Logger.Debug(zbcontext.QuoteCommand("→ go", args))
+
+		ecmd = exec.Command("go", args...) // nosec
+		ecmd.Env = env
+		ecmd.Stdout = pw
+		ecmd.Stderr = pw
+		if err := ecmd.Start(); err != nil {
+			return 0, err
+		}
+	}
+
+	code := zbcontext.ExitOK
+	var group errgroup.Group
+	group.Go(func() error {
+		defer func() { _ = pw.Close() }() // nosec
+
+		if ecmd == nil {
+			return nil
+		}
+
+		ecode, err := zbcontext.ExitCode(ecmd.Wait())
+		if err != nil {
+			return err
+		}
+
+		if code == zbcontext.ExitOK {
+			code = ecode
+		}
+
+		return nil
+	})
+
+	r := bufio.NewReader(io.TeeReader(pr, w))
+
+	for _, pkg := range pkgs {
+		var err error
+
+		if len(toRun) > 0 && toRun[0] == pkg {
+			err = fn(pkg, r)
+			toRun = toRun[1:]
+		} else {
+			err = fn(pkg, nil)
+		}
+
+		if err != nil {
+			return code, err
+		}
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		return code, err
+	}
+
+	if err := group.Wait(); err != nil {
+		return code, err
+	}
+
+	return code, nil
+}
@@ -0,0 +1,298 @@
+package zbtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+// maxSamples is the number of durations retained for each package or test
+const maxSamples = 20
+
+// Sample is a single recorded duration
+type Sample struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Failed   bool          `json:"failed,omitempty"`
+}
+
+// History is the record of how long package and test runs took in previous
+// invocations of zb test
+type History struct {
+	Packages map[string][]Sample `json:"packages"`
+	Tests    map[string][]Sample `json:"tests"`
+}
+
+var testRE = regexp.MustCompile(`\A\s*--- (PASS|FAIL|SKIP): ([^ \t]+) \(([0-9.]+s)\)\n\z`)
+
+func historyFile(ctx zbcontext.Context) string {
+	return filepath.Join(ctx.CacheDir, "history.json")
+}
+
+func loadHistory(ctx zbcontext.Context) (*History, error) {
+	h := History{
+		Packages: map[string][]Sample{},
+		Tests:    map[string][]Sample{},
+	}
+
+	data, err := ioutil.ReadFile(historyFile(ctx))
+	if os.IsNotExist(err) {
+		return &h, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &h); err != nil {
+		ctx.Logger.WithError(err).Warn("discarding unreadable test history")
+		return &History{
+			Packages: map[string][]Sample{},
+			Tests:    map[string][]Sample{},
+		}, nil
+	}
+
+	if h.Packages == nil {
+		h.Packages = map[string][]Sample{}
+	}
+
+	if h.Tests == nil {
+		h.Tests = map[string][]Sample{}
+	}
+
+	return &h, nil
+}
+
+func appendSample(m map[string][]Sample, key string, s Sample) {
+	samples := append(m[key], s)
+	if len(samples) > maxSamples {
+		samples = samples[len(samples)-maxSamples:]
+	}
+	m[key] = samples
+}
+
+// History returns the test duration history, loading it from the cache
+// directory if necessary
+func (t *ZBTest) History(ctx zbcontext.Context) (*History, error) {
+	if t.history != nil {
+		return t.history, nil
+	}
+
+	h, err := loadHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t.history = h
+	return h, nil
+}
+
+func (t *ZBTest) recordPackage(ctx zbcontext.Context, importPath, status, elapsed string) error {
+	d, err := time.ParseDuration(elapsed)
+	if err != nil {
+		// e.g. [no test files] or [build failed], the durations of any
+		// tests that did run belong to this package and are dropped with
+		// it
+		t.pending = nil
+		return nil
+	}
+
+	h, err := t.History(ctx)
+	if err != nil {
+		return err
+	}
+
+	suffix := ""
+	if t.Variant != nil {
+		suffix = " [" + t.Variant.Name + "]"
+	}
+
+	appendSample(h.Packages, importPath+suffix, Sample{
+		Time:     time.Now(),
+		Duration: d,
+		Failed:   status == "FAIL",
+	})
+
+	for name, s := range t.pending {
+		appendSample(h.Tests, importPath+"."+name+suffix, s)
+	}
+
+	t.pending = nil
+
+	return nil
+}
+
+func (t *ZBTest) recordTest(line string) {
+	m := testRE.FindStringSubmatch(line)
+	if m == nil || m[1] == "SKIP" {
+		return
+	}
+
+	d, err := time.ParseDuration(m[3])
+	if err != nil {
+		return
+	}
+
+	if t.pending == nil {
+		t.pending = map[string]Sample{}
+	}
+
+	t.pending[m[2]] = Sample{
+		Time:     time.Now(),
+		Duration: d,
+		Failed:   m[1] == "FAIL",
+	}
+}
+
+// SaveHistory writes any durations recorded by ReadResult to the cache
+// directory
+func (t *ZBTest) SaveHistory(ctx zbcontext.Context) error {
+	if t.history == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(t.history)
+	if err != nil {
+		return err
+	}
+
+	file := historyFile(ctx)
+
+	if err = os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, data, 0600)
+}
+
+// Report types that can be passed to ShowReport
+const (
+	ReportSlow = "slow"
+)
+
+type entry struct {
+	name    string
+	samples []Sample
+}
+
+func (e entry) latest() time.Duration {
+	return e.samples[len(e.samples)-1].Duration
+}
+
+func (e entry) average() time.Duration {
+	var total time.Duration
+	for _, s := range e.samples {
+		total += s.Duration
+	}
+	return total / time.Duration(len(e.samples))
+}
+
+// trend compares the latest duration to the average of the ones that came
+// before it
+func (e entry) trend() string {
+	if len(e.samples) < 2 {
+		return "new"
+	}
+
+	var total time.Duration
+	prev := e.samples[:len(e.samples)-1]
+	for _, s := range prev {
+		total += s.Duration
+	}
+
+	avg := total / time.Duration(len(prev))
+	if avg == 0 {
+		return "~"
+	}
+
+	pct := 100 * float64(e.latest()-avg) / float64(avg)
+	if pct > -1 && pct < 1 {
+		return "~"
+	}
+
+	return fmt.Sprintf("%+.1f%%", pct)
+}
+
+func sortedEntries(m map[string][]Sample, top int) []entry {
+	var entries []entry
+	for name, samples := range m {
+		if len(samples) == 0 {
+			continue
+		}
+		entries = append(entries, entry{name: name, samples: samples})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		li, lj := entries[i].latest(), entries[j].latest()
+		if li != lj {
+			return li > lj
+		}
+		return entries[i].name < entries[j].name
+	})
+
+	if top > 0 && len(entries) > top {
+		entries = entries[:top]
+	}
+
+	return entries
+}
+
+func writeEntries(w io.Writer, title string, entries []entry) error {
+	fmt.Fprintf(w, "%s\n", title)
+
+	if len(entries) == 0 {
+		fmt.Fprintf(w, "  (no history)\n\n")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "  LATEST\tAVERAGE\tTREND\tRUNS\tNAME\n")
+
+	for _, e := range entries {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%d\t%s\n",
+			e.latest(),
+			e.average(),
+			e.trend(),
+			len(e.samples),
+			e.name,
+		)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	return nil
+}
+
+// ShowReport writes the named report, built from the test duration history, to
+// the Writer. Only the top entries are included if top is greater than 0.
+func (t *ZBTest) ShowReport(ctx zbcontext.Context, w io.Writer, report string, top int) error {
+	if report != ReportSlow {
+		return errors.Errorf("unknown report: %s (available: %s)", report, strings.Join([]string{ReportSlow}, ", "))
+	}
+
+	h, err := t.History(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = writeEntries(w, "SLOWEST PACKAGES", sortedEntries(h.Packages, top)); err != nil {
+		return err
+	}
+
+	return writeEntries(w, "SLOWEST TESTS", sortedEntries(h.Tests, top))
+}
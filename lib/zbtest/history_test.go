@@ -0,0 +1,153 @@
+package zbtest
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"jrubin.io/zb/lib/zbcontext"
+)
+
+func TestRecordTest(t *testing.T) {
+	tests := []struct {
+		line     string
+		name     string
+		duration time.Duration
+		failed   bool
+	}{
+		{"--- PASS: TestA (0.50s)\n", "TestA", 500 * time.Millisecond, false},
+		{"    --- FAIL: TestB/sub (1.25s)\n", "TestB/sub", 1250 * time.Millisecond, true},
+		{"--- SKIP: TestC (0.00s)\n", "", 0, false},
+		{"=== RUN   TestD\n", "", 0, false},
+		{"--- PASS: TestE (0.10s) trailing\n", "", 0, false},
+	}
+
+	for _, test := range tests {
+		var zt ZBTest
+		zt.recordTest(test.line)
+
+		if test.name == "" {
+			if len(zt.pending) != 0 {
+				t.Errorf("recordTest(%q) recorded %v, want nothing", test.line, zt.pending)
+			}
+			continue
+		}
+
+		s, ok := zt.pending[test.name]
+		if !ok || s.Duration != test.duration || s.Failed != test.failed {
+			t.Errorf("recordTest(%q) recorded %v, want %s %v failed=%v", test.line, zt.pending, test.name, test.duration, test.failed)
+		}
+	}
+}
+
+func TestRecordPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	ctx := zbcontext.Context{CacheDir: dir}
+
+	var zt ZBTest
+
+	// the tests of a package without a duration aren't attributed to the
+	// next package
+	zt.recordTest("--- PASS: TestBroken (0.10s)\n")
+	if err = zt.recordPackage(ctx, "example.com/broken", "FAIL", "[build failed]"); err != nil {
+		t.Fatal(err)
+	}
+
+	zt.recordTest("--- PASS: TestA (0.50s)\n")
+	zt.recordTest("--- FAIL: TestB (0.25s)\n")
+	if err = zt.recordPackage(ctx, "example.com/a", "FAIL", "0.80s"); err != nil {
+		t.Fatal(err)
+	}
+
+	zt.Variant = &Variant{Name: "race"}
+	zt.recordTest("--- PASS: TestA (1.50s)\n")
+	if err = zt.recordPackage(ctx, "example.com/a", "ok", "1.60s"); err != nil {
+		t.Fatal(err)
+	}
+
+	if zt.pending != nil {
+		t.Errorf("pending = %v, want nil", zt.pending)
+	}
+
+	want := History{
+		Packages: map[string][]Sample{
+			"example.com/a":        {{Duration: 800 * time.Millisecond, Failed: true}},
+			"example.com/a [race]": {{Duration: 1600 * time.Millisecond}},
+		},
+		Tests: map[string][]Sample{
+			"example.com/a.TestA":        {{Duration: 500 * time.Millisecond}},
+			"example.com/a.TestB":        {{Duration: 250 * time.Millisecond, Failed: true}},
+			"example.com/a.TestA [race]": {{Duration: 1500 * time.Millisecond}},
+		},
+	}
+
+	checkHistory(t, zt.history, &want)
+
+	if err = zt.SaveHistory(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadHistory(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkHistory(t, loaded, &want)
+
+	for name, samples := range zt.history.Packages {
+		if got := loaded.Packages[name][0].Time; !got.Equal(samples[0].Time) {
+			t.Errorf("time of %s = %v, want %v", name, got, samples[0].Time)
+		}
+	}
+}
+
+func TestAppendSample(t *testing.T) {
+	m := map[string][]Sample{}
+	for i := 0; i < maxSamples+5; i++ {
+		appendSample(m, "x", Sample{Duration: time.Duration(i)})
+	}
+
+	if len(m["x"]) != maxSamples {
+		t.Fatalf("kept %d samples, want %d", len(m["x"]), maxSamples)
+	}
+
+	if first := m["x"][0].Duration; first != 5 {
+		t.Errorf("oldest sample = %d, want 5", first)
+	}
+}
+
+// checkHistory compares the durations and failures of the samples, ignoring
+// their times
+func checkHistory(t *testing.T, got, want *History) {
+	t.Helper()
+
+	check := func(kind string, got, want map[string][]Sample) {
+		if len(got) != len(want) {
+			t.Errorf("%s = %v, want %v", kind, got, want)
+			return
+		}
+
+		for name, ws := range want {
+			gs := got[name]
+			if len(gs) != len(ws) {
+				t.Errorf("%s[%s] = %v, want %v", kind, name, gs, ws)
+				continue
+			}
+
+			for i := range ws {
+				if gs[i].Duration != ws[i].Duration || gs[i].Failed != ws[i].Failed {
+					t.Errorf("%s[%s][%d] = %v, want %v", kind, name, i, gs[i], ws[i])
+				}
+			}
+		}
+	}
+
+	check("packages", got.Packages, want.Packages)
+	check("tests", got.Tests, want.Tests)
+}
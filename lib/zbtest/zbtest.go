@@ -2,6 +2,7 @@ package zbtest
 
 import (
 	"bytes"
+	"crypto/sha1"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -18,7 +19,11 @@ import (
 // ZBTest provides methods for working with cached test result files
 type ZBTest struct {
 	buildflags.TestFlagsData
-	Force bool
+	Force   bool
+	Variant *Variant
+
+	history *History
+	pending map[string]Sample
 }
 
 func (t *ZBTest) TestSetup(ctx zbcontext.Context) zbcontext.Context {
@@ -30,7 +35,54 @@ func (t *ZBTest) TestSetup(ctx zbcontext.Context) zbcontext.Context {
 	return ctx
 }
 
-var endRE = regexp.MustCompile(`\A(\?|ok|FAIL) {0,3}\t([^ \t]+)[ \t]([0-9.]+s|\[.*\])\n\z`)
+var endRE = regexp.MustCompile(`\A(\?|ok|FAIL) {0,3}\t([^ \t]+)[ \t]([0-9.]+s|\[.*\]|\(cached\))\n\z`)
+
+// Variant is a named set of additional flags, build tags and environment
+// variables that tests can be run with. Results of each Variant are cached
+// separately.
+type Variant struct {
+	Name  string
+	Flags []string
+	Tags  []string
+	Env   []string
+}
+
+func (v *Variant) key() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "variant %s\n", v.Name)
+	for _, f := range v.Flags {
+		fmt.Fprintf(&buf, "flag %s\n", f)
+	}
+	for _, t := range v.Tags {
+		fmt.Fprintf(&buf, "tag %s\n", t)
+	}
+	for _, e := range v.Env {
+		fmt.Fprintf(&buf, "env %s\n", e)
+	}
+	return buf.String()
+}
+
+// RunArgs returns the arguments to pass to go test, including those of the
+// Variant, if any
+func (t *ZBTest) RunArgs() []string {
+	if t.Variant == nil {
+		return t.TestFlagsData.TestArgs(nil, nil)
+	}
+
+	f := t.TestFlagsData
+	f.Tags = append(append([]string{}, f.Tags...), t.Variant.Tags...)
+
+	return append(f.TestArgs(nil, nil), t.Variant.Flags...)
+}
+
+// Env returns the environment that go test should be run with
+func (t *ZBTest) Env() []string {
+	if t.Variant == nil || len(t.Variant.Env) == 0 {
+		return nil
+	}
+
+	return append(os.Environ(), t.Variant.Env...)
+}
 
 // CacheFile returns the location of the test cache file for a given package
 func (t *ZBTest) CacheFile(ctx zbcontext.Context, p *project.Package) (string, error) {
@@ -39,6 +91,21 @@ func (t *ZBTest) CacheFile(ctx zbcontext.Context, p *project.Package) (string, e
 		return "", err
 	}
 
+	if t.Variant != nil {
+		// files excluded by the default build constraints may be included
+		// by the tags or environment of the variant
+		var ignoredHash string
+		if ignoredHash, err = p.IgnoredHash(); err != nil {
+			return "", err
+		}
+
+		h := sha1.New()
+		fmt.Fprintf(h, "test %s\n", testHash)
+		fmt.Fprintf(h, "ignored %s\n", ignoredHash)
+		fmt.Fprintf(h, "%s", t.Variant.key())
+		testHash = fmt.Sprintf("%x", h.Sum(nil))
+	}
+
 	return filepath.Join(
 		ctx.CacheDir,
 		testHash[:3],
@@ -67,14 +134,25 @@ type StringReader interface {
 	ReadString(byte) (string, error)
 }
 
-// ReadResult from the StringReader and write it to the CacheFile for the
-// given package
-func (t *ZBTest) ReadResult(ctx zbcontext.Context, r StringReader, p *project.Package) error {
-	file, err := t.CacheFile(ctx, p)
-	if err != nil {
-		return err
-	}
+// Status is the final line that go test emits for each package
+type Status struct {
+	Result     string
+	ImportPath string
+	Elapsed    string
+	Cached     bool
+}
 
+// Passed reports whether the package passed its tests
+func (s *Status) Passed() bool {
+	return s.Result != "FAIL"
+}
+
+// ReadPackage reads the output of a single package from the StringReader.
+// Each line preceding the final status line is passed to fn, if it is not nil.
+// The returned data has the status line marked as cached so that it is suitable
+// for writing to a cache file. If the output ended before a status line was
+// found, the returned Status is nil.
+func ReadPackage(r StringReader, fn func(line string)) ([]byte, *Status, error) {
 	var buf bytes.Buffer
 
 	for eof := false; !eof; {
@@ -82,55 +160,84 @@ func (t *ZBTest) ReadResult(ctx zbcontext.Context, r StringReader, p *project.Pa
 		if err == io.EOF {
 			eof = true
 		} else if err != nil {
-			return err
+			return nil, nil, err
 		}
 
 		m := endRE.FindStringSubmatch(line)
 		if m == nil {
+			if fn != nil {
+				fn(line)
+			}
 			if _, err := buf.WriteString(line); err != nil {
-				return err
+				return nil, nil, err
 			}
 			continue
 		}
 
 		fmt.Fprintf(&buf, "%s (cached)\n", strings.TrimSuffix(line, "\n"))
 
-		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
-			return err
-		}
+		return buf.Bytes(), &Status{
+			Result:     m[1],
+			ImportPath: m[2],
+			Elapsed:    m[3],
+		}, nil
+	}
 
-		if err := ioutil.WriteFile(file, buf.Bytes(), 0600); err != nil {
-			return err
-		}
+	return buf.Bytes(), nil, nil
+}
+
+// ReadResult from the StringReader and write it to the CacheFile for the
+// given package. The returned Status is nil if the output ended before the
+// status line of the package was found.
+func (t *ZBTest) ReadResult(ctx zbcontext.Context, r StringReader, p *project.Package) (*Status, error) {
+	file, err := t.CacheFile(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	data, status, err := ReadPackage(r, t.recordTest)
+	if err != nil || status == nil {
+		return nil, err
+	}
+
+	if err = t.recordPackage(ctx, status.ImportPath, status.Result, status.Elapsed); err != nil {
+		return nil, err
+	}
 
-		break
+	if err = os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return status, ioutil.WriteFile(file, data, 0600)
 }
 
 // ShowResult reads the CacheFile for the given package and writes it to the
 // Writer
-func (t *ZBTest) ShowResult(ctx zbcontext.Context, w io.Writer, p *project.Package) (bool, error) {
+func (t *ZBTest) ShowResult(ctx zbcontext.Context, w io.Writer, p *project.Package) (*Status, error) {
 	file, err := t.CacheFile(ctx, p)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	data, err := ioutil.ReadFile(file)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+
+	if _, err = w.Write(data); err != nil {
+		return nil, err
 	}
 
 	check := bytes.TrimSpace(data)
 	i := bytes.LastIndex(check, []byte{'\n'})
-	line := check[i+1:]
+	line := strings.TrimSuffix(string(check[i+1:]), " (cached)") + "\n"
 
-	if bytes.HasPrefix(line, []byte("FAIL")) {
-		_, err = w.Write(data)
-		return false, err
+	status := Status{Cached: true}
+	if m := endRE.FindStringSubmatch(line); m != nil {
+		status.Result, status.ImportPath, status.Elapsed = m[1], m[2], m[3]
+	} else if strings.HasPrefix(line, "FAIL") {
+		status.Result = "FAIL"
 	}
 
-	_, err = w.Write(data)
-	return true, err
+	return &status, nil
 }
@@ -9,10 +9,13 @@ import (
 	"jrubin.io/slog"
 	"jrubin.io/slog/handlers/text"
 	"jrubin.io/zb/cmd"
+	"jrubin.io/zb/cmd/bench"
 	"jrubin.io/zb/cmd/build"
+	"jrubin.io/zb/cmd/checkdirectives"
 	"jrubin.io/zb/cmd/clean"
 	"jrubin.io/zb/cmd/commands"
 	"jrubin.io/zb/cmd/complete"
+	"jrubin.io/zb/cmd/generate"
 	"jrubin.io/zb/cmd/install"
 	"jrubin.io/zb/cmd/lint"
 	"jrubin.io/zb/cmd/list"
@@ -44,10 +47,13 @@ var (
 )
 
 var subcommands = []cmd.Constructor{
+	bench.Cmd,
 	build.Cmd,
+	checkdirectives.Cmd,
 	clean.Cmd,
 	commands.Cmd,
 	complete.Cmd,
+	generate.Cmd,
 	install.Cmd,
 	lint.Cmd,
 	list.Cmd,